@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -13,8 +15,8 @@ import (
 
 func whatKey(fileMap gosnap.FileMapType) error {
 	for _, file := range fileMap {
-		if file.Data["key"] != nil {
-			file.Content = []byte("key: " + file.Data["key"].(string))
+		if key, err := file.Data.String("key"); err == nil {
+			file.Content = []byte("key: " + key)
 		}
 	}
 
@@ -32,22 +34,50 @@ func getDirectory() string {
 	return path.Dir(filename)
 }
 
+var watch = flag.Bool("watch", false, "keep rebuilding when files in source change")
+var serve = flag.String("serve", "", "serve the site from memory on this address instead of writing it out")
+var drafts = flag.Bool("drafts", false, "include files marked as drafts")
+
 func main() {
+	flag.Parse()
+
 	directory := getDirectory()
 
 	site := gosnap.GoSnap{
-		Source:      path.Join(directory, "source"),
-		Destination: path.Join(directory, "destination"),
-		Clean:       true,
-		Logger:      log.New(os.Stderr, "Snap: ", log.Lshortfile|log.Ldate|log.Ltime),
+		Source:        path.Join(directory, "source"),
+		Destination:   path.Join(directory, "destination"),
+		MetadataFile:  path.Join(directory, "site.yaml"),
+		DataDirectory: "data",
+		Prune:         true,
+		Drafts:        *drafts,
+		Logger:        log.New(os.Stderr, "Snap: ", log.Lshortfile|log.Ldate|log.Ltime),
 	}
 
 	site.Use(whatKey)
+	site.Use(plugins.Markdown)
+	site.Use(plugins.Permalinks(plugins.Permalink{Pattern: ":collection/:year/:slug/", Collection: "posts"}))
+	posts := plugins.Collection{Name: "posts", SortBy: "date", Reverse: true}
+
+	site.Use(plugins.Collections(posts))
+	site.Use(plugins.Paginate(plugins.Pagination{Collection: posts, PerPage: 10, Template: "index.html", Path: "page/:num/index.html"}))
 	site.Use(plugins.Render)
+	site.Use(plugins.Feed(plugins.FeedConfig{Collection: posts, BaseURL: "https://example.com", Title: "Go Snap example", Author: "gosnap"}))
+	site.Use(plugins.Redirects(plugins.RedirectFiles{Netlify: "_redirects", Nginx: "redirects.nginx.conf", Stubs: true}))
+	site.Use(plugins.Sitemap(plugins.SitemapConfig{BaseURL: "https://example.com", Robots: []plugins.RobotsRule{{Disallow: []string{"/nest/"}}}}))
 	site.Use(plugins.MinifyCSS)
 	site.Use(plugins.MinifyJS)
+	site.Use(plugins.ServerHeaders(plugins.DefaultHeaderFiles))
+	site.Use(plugins.Precompress(plugins.PrecompressConfig{MinSize: 256}))
 
-	err := site.Build()
+	var err error
+
+	if *serve != "" {
+		err = site.Serve(*serve)
+	} else if *watch {
+		err = site.Watch(context.Background())
+	} else {
+		err = site.Build()
+	}
 
 	if err != nil {
 		fmt.Printf("Error running build. %v", err)
@@ -1,11 +1,16 @@
 package gosnap
 
 import (
+	"context"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"reflect"
+	"regexp"
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -13,17 +18,40 @@ import (
 // All the types its fit to print
 type Plugin func(FileMapType) error
 
+// Plugin which only looks at one file at a time so that it can be run on many files in parallel,
+// it must not add or remove files from the map
+type FilePlugin func(string, *GoSnapFile) error
+
 type GoSnapFile struct {
 	Content  []byte
 	FileInfo os.FileInfo
 	Data     FrontmatterValueType
-	Headers  HeaderGetter
+	// read and changed through Headers
+	header http.Header
+	// Content as it was when the file was last synced, see sync
+	synced contentState
+	// path the file was read from, empty for files created by plugins
+	SourcePath string
+	// passthrough files are never loaded into Content, they are copied from SourcePath when written
+	Passthrough bool
+	// names of the plugins which replaced Content, in the order they ran
+	ModifiedBy []string
+}
+
+// NewFile creates a file for plugins which generate files that were never read from Source
+func NewFile(filePath string, content []byte) *GoSnapFile {
+	header, _ := newHeaders(filePath, nil)
+	file := &GoSnapFile{Content: content, header: header}
+	file.sync(filePath)
+
+	return file
 }
 
 // Implement io.Writer interface so that plugins can write to the file as if it is a real file
 // note: since this only appends if you want to overwrite you need to clear the file first
 func (gsf *GoSnapFile) Write(p []byte) (n int, err error) {
 	gsf.Content = append(gsf.Content, p...)
+	gsf.sync("")
 
 	return len(p), nil
 }
@@ -35,8 +63,43 @@ func (gsf *GoSnapFile) Read(p []byte) (n int, err error) {
 	return len(p), io.EOF
 }
 
+// Copy returns a new file with its own Content, headers and top level Data so that plugins can modify it freely
+func (gsf *GoSnapFile) Copy() *GoSnapFile {
+	file := *gsf
+
+	if gsf.Content != nil {
+		file.Content = append([]byte{}, gsf.Content...)
+	}
+
+	file.Data = gsf.Data.Copy()
+	file.header = copyHeader(gsf.header)
+
+	// the copy starts out in sync with its own Content, which is the same
+	if info, ok := gsf.FileInfo.(*FileInfo); ok {
+		copiedInfo := *info
+		file.FileInfo = &copiedInfo
+	}
+
+	if gsf.ModifiedBy != nil {
+		file.ModifiedBy = append([]string{}, gsf.ModifiedBy...)
+	}
+
+	return &file
+}
+
 type FileMapType map[string]*GoSnapFile
 
+// Copy returns a new map containing copies of every file in fileMap
+func (fileMap FileMapType) Copy() FileMapType {
+	copied := make(FileMapType, len(fileMap))
+
+	for filePath, file := range fileMap {
+		copied[filePath] = file.Copy()
+	}
+
+	return copied
+}
+
 type StringSet map[string]struct{}
 
 // Exposed API
@@ -45,11 +108,15 @@ type GoSnapFunctionality interface {
 	ReadFile(string, os.FileInfo) // defined in gosnap_read.go
 	Ignore(string)                // defined in gosnap_read.go
 	IgnoreAll(...string)          // defined in gosnap_read.go
+	IgnorePattern(...string)      // defined in gosnap_ignore.go
 	Write()                       // defined in gosnap_write.go
 	WriteFile(string, GoSnapFile) // defined in gosnap_write.go
 	Use(Plugin)
 	UseAll(...Plugin)
+	UseEach(...FilePlugin)
 	Build()
+	Watch(context.Context) // defined in gosnap_watch.go
+	Serve(string)          // defined in gosnap_serve.go
 }
 
 // Structure of the main object
@@ -57,9 +124,37 @@ type GoSnap struct {
 	Source      string
 	Destination string
 	Clean       bool
-	IgnoreMap   StringSet
-	FileMap     FileMapType
-	Plugins     []Plugin
+	// remove files from Destination which were not written by the last build, without wiping everything like Clean
+	Prune bool
+	// where each build records what it wrote, so that the next one can skip files which did not change and Prune
+	// knows what it may remove. Defaults to Destination with RECORD_EXTENSION so it is not published with the site.
+	Record string
+	// path inside of Destination to write a JSON description of every written file to, nothing is written if empty
+	Manifest string
+	// available to every file as "site" in its frontmatter
+	Metadata FrontmatterValueType
+	// optional YAML, JSON or TOML file with more metadata, it is not written out even if it is inside of Source
+	MetadataFile string
+	// directory inside of Source with YAML, JSON, TOML and CSV files which are not written out,
+	// instead their contents are added to the metadata as "data", for example data/team.yaml becomes {{ .site.data.team }}
+	DataDirectory string
+	// build files with draft: true in their frontmatter as well, for previews. Files with a publishDate
+	// in the future are always left out until it has passed.
+	Drafts bool
+	// patterns for files which are not read but copied straight to Destination, see BinaryPatterns
+	Passthrough []string
+	// how often Watch checks Source for changes, defaults to DEFAULT_WATCH_INTERVAL
+	WatchInterval time.Duration
+	// exact walked paths which are not read, see Ignore
+	IgnoreMap StringSet
+	// gitignore style patterns on paths inside of Source, added to by the IGNORE_FILE in Source if there is one
+	IgnorePatterns []string
+	// paths inside of Source matching any of these are not read
+	IgnoreExpressions []*regexp.Regexp
+	FileMap           FileMapType
+	Plugins           []Plugin
+	// the metadata every file of the current build shares as "site", see attachMetadata
+	site FrontmatterValueType
 	*log.Logger
 }
 
@@ -77,17 +172,159 @@ func (gs *GoSnap) UseAll(plugins ...Plugin) {
 	}
 }
 
+// UseEach adds file plugins as a single parallel step, see Each
+func (gs *GoSnap) UseEach(plugins ...FilePlugin) {
+	gs.Use(Each(plugins...))
+}
+
 // from https://stackoverflow.com/questions/7052693/how-to-get-the-name-of-a-function-in-go
 func getFunctionName(i interface{}) string {
 	return runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 }
 
+// EachN turns file plugins into a Plugin which runs them over every file using at most workers goroutines,
+// each file goes through the file plugins in order and every file is done before the Plugin returns
+func EachN(workers int, plugins ...FilePlugin) Plugin {
+	if workers < 1 {
+		workers = 1
+	}
+
+	pluginNames := make([]string, len(plugins))
+	for i, plugin := range plugins {
+		pluginNames[i] = getFunctionName(plugin)
+	}
+
+	return func(fileMap FileMapType) error {
+		filePaths := make(chan string)
+		var wg sync.WaitGroup
+		var once sync.Once
+		var firstErr error
+		failed := make(chan struct{})
+
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for filePath := range filePaths {
+					file := fileMap[filePath]
+					file.syncFirst(filePath)
+
+					for i, plugin := range plugins {
+						if err := runFilePlugin(filePath, file, pluginNames[i], plugin); err != nil {
+							once.Do(func() {
+								firstErr = err
+								close(failed)
+							})
+							break
+						}
+					}
+				}
+			}()
+		}
+
+	send:
+		for filePath := range fileMap {
+			select {
+			case filePaths <- filePath:
+			case <-failed:
+				break send
+			}
+		}
+
+		close(filePaths)
+		wg.Wait()
+
+		return firstErr
+	}
+}
+
+// Each runs file plugins over every file with one worker per CPU
+func Each(plugins ...FilePlugin) Plugin {
+	return EachN(runtime.NumCPU(), plugins...)
+}
+
+// adds pluginName to ModifiedBy if the plugin changed a file which existed before it ran, or added the file,
+// unless a plugin it ran itself already recorded the change. recorded is the length of ModifiedBy before it ran.
+func recordModification(file *GoSnapFile, changed bool, exists bool, recorded int, pluginName string) {
+	if exists && changed && len(file.ModifiedBy) == recorded {
+		file.ModifiedBy = append(file.ModifiedBy, pluginName)
+	} else if !exists && len(file.ModifiedBy) == 0 {
+		file.ModifiedBy = append(file.ModifiedBy, pluginName)
+	}
+}
+
+// error from a plugin which already says which plugin it came from, so that plugins running it do not say so again
+type pluginError struct {
+	error
+}
+
+func (err pluginError) Cause() error {
+	return errors.Cause(err.error)
+}
+
+// runs the plugin and records it in ModifiedBy of the files it changed or added
+func runPlugin(fileMap FileMapType, pluginName string, plugin Plugin) error {
+	// files are tracked by identity since plugins can rename them
+	recorded := make(map[*GoSnapFile]int, len(fileMap))
+	for filePath, file := range fileMap {
+		file.syncFirst(filePath)
+		recorded[file] = len(file.ModifiedBy)
+	}
+
+	if err := plugin(fileMap); err != nil {
+		if _, named := err.(pluginError); named {
+			return err
+		}
+
+		return pluginError{errors.Wrapf(err, "Error in plugin %v", pluginName)}
+	}
+
+	for filePath, file := range fileMap {
+		before, exists := recorded[file]
+		recordModification(file, file.sync(filePath), exists, before, pluginName)
+	}
+
+	return nil
+}
+
+// runs the file plugin on one file and records it in ModifiedBy if it changed the file
+func runFilePlugin(filePath string, file *GoSnapFile, pluginName string, plugin FilePlugin) error {
+	recorded := len(file.ModifiedBy)
+
+	if err := plugin(filePath, file); err != nil {
+		if _, named := err.(pluginError); named {
+			return err
+		}
+
+		return pluginError{errors.Wrapf(err, "Error in file plugin %v for %v", pluginName, filePath)}
+	}
+
+	recordModification(file, file.sync(filePath), true, recorded, pluginName)
+
+	return nil
+}
+
+// Named gives a plugin the name it is reported under in errors and ModifiedBy. Otherwise that is the name of its
+// function, which is the same for every plugin one constructor returns.
+func Named(name string, plugin Plugin) Plugin {
+	return func(fileMap FileMapType) error {
+		return runPlugin(fileMap, name, plugin)
+	}
+}
+
+// NamedFile gives a file plugin a name like Named
+func NamedFile(name string, plugin FilePlugin) FilePlugin {
+	return func(filePath string, file *GoSnapFile) error {
+		return runFilePlugin(filePath, file, name, plugin)
+	}
+}
+
 func Run(fileMap FileMapType, plugins []Plugin) error {
 	for _, plugin := range plugins {
-		pluginName := getFunctionName(plugin)
-
-		if err := plugin(fileMap); err != nil {
-			return errors.Wrapf(err, "Error in plugin %v", pluginName)
+		if err := runPlugin(fileMap, getFunctionName(plugin), plugin); err != nil {
+			return err
 		}
 	}
 
@@ -103,11 +340,20 @@ func (gs *GoSnap) Build() (err error) {
 		return errors.Wrap(err, "Build failed at read step")
 	}
 
+	return gs.process()
+}
+
+// runs the plugins over FileMap and writes the result out
+func (gs *GoSnap) process() (err error) {
 	gs.Print("run files through plugins")
-	err = Run(gs.FileMap, gs.Plugins)
 
-	if err != nil {
-		return errors.Wrap(err, "Build failed during plugin run")
+	for _, plugin := range gs.Plugins {
+		if err = runPlugin(gs.FileMap, getFunctionName(plugin), plugin); err != nil {
+			return errors.Wrap(err, "Build failed during plugin run")
+		}
+
+		// so that files added by the plugin have the site metadata as well by the time they are rendered
+		gs.shareMetadata(gs.FileMap)
 	}
 
 	gs.Printf("write out %v files", len(gs.FileMap))
@@ -117,5 +363,13 @@ func (gs *GoSnap) Build() (err error) {
 		return errors.Wrap(err, "Build failed writing files")
 	}
 
+	if gs.Manifest != "" {
+		gs.Printf("write manifest to %v", gs.Manifest)
+
+		if err = gs.writeManifest(); err != nil {
+			return errors.Wrap(err, "Build failed writing manifest")
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,93 @@
+package gosnap
+
+import (
+	"os"
+	"path"
+	"time"
+)
+
+// FileInfo is how gosnap describes a file in memory, it follows the file as plugins rename it and change
+// its Content instead of staying as it was on disk. Files get one when they are read, and files added by
+// plugins get one after the plugin that added them ran, plugins can also set their own with NewFileInfo.
+type FileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	sys     interface{}
+}
+
+func NewFileInfo(name string, size int64, mode os.FileMode, modTime time.Time) *FileInfo {
+	return &FileInfo{name: name, size: size, mode: mode, modTime: modTime}
+}
+
+// takes over everything from another os.FileInfo, which stays available as Sys
+func fileInfoFrom(info os.FileInfo) *FileInfo {
+	if fileInfo, ok := info.(*FileInfo); ok {
+		return fileInfo
+	}
+
+	return &FileInfo{name: info.Name(), size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), sys: info}
+}
+
+func (fi *FileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *FileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *FileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi *FileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *FileInfo) IsDir() bool {
+	return false
+}
+
+func (fi *FileInfo) Sys() interface{} {
+	return fi.sys
+}
+
+// brings FileInfo up to date with the file as it is now at filePath. The modification time is left alone so
+// that builds are repeatable, Write moves it once it knows whether the output differs from the previous build.
+func (gsf *GoSnapFile) syncFileInfo(filePath string) {
+	if gsf.FileInfo == nil {
+		gsf.FileInfo = NewFileInfo("", int64(len(gsf.Content)), DEFAULT_PERM, timeNow())
+	}
+
+	info := fileInfoFrom(gsf.FileInfo)
+	gsf.FileInfo = info
+
+	// files written to through io.Writer do not know where they are
+	if filePath != "" {
+		info.name = path.Base(filePath)
+	}
+
+	// passthrough files are never loaded so their size on disk is all there is
+	if !gsf.Passthrough {
+		info.size = int64(len(gsf.Content))
+	}
+}
+
+// output which is the same as what the previous build wrote keeps the modification time it had then, output
+// which differs was modified now, and anything else keeps the time of its source
+func (gsf *GoSnapFile) settleModTime(previous recordedFile, recorded bool) {
+	if gsf.Passthrough || !recorded {
+		return
+	}
+
+	info := fileInfoFrom(gsf.FileInfo)
+	gsf.FileInfo = info
+
+	if previous.MD5 == recordOf(gsf).MD5 {
+		info.modTime = previous.ModTime
+	} else {
+		info.modTime = timeNow()
+	}
+}
@@ -0,0 +1,210 @@
+package gosnap
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Values from the frontmatter of a file, nested maps are FrontmatterValueType as well no matter the format
+type FrontmatterValueType map[string]interface{}
+
+// returned, wrapped, by the getters when a key is not there at all, check with errors.Cause
+var ErrMissingKey = errors.New("Missing frontmatter key")
+
+// formats dates in frontmatter are tried in
+var DateFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// Copy returns a new map with the same values, nested values are shared
+func (values FrontmatterValueType) Copy() FrontmatterValueType {
+	if values == nil {
+		return nil
+	}
+
+	copied := make(FrontmatterValueType, len(values))
+
+	for key, value := range values {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+func (values FrontmatterValueType) Has(key string) bool {
+	_, exists := values[key]
+
+	return exists
+}
+
+func (values FrontmatterValueType) get(key string) (interface{}, error) {
+	value, exists := values[key]
+
+	if !exists {
+		return nil, errors.Wrapf(ErrMissingKey, "Frontmatter has no %v", key)
+	}
+
+	return value, nil
+}
+
+func wrongType(key string, value interface{}, expected string) error {
+	return errors.Errorf("Frontmatter %v is %v which is not a %v", key, value, expected)
+}
+
+func (values FrontmatterValueType) String(key string) (string, error) {
+	value, err := values.get(key)
+	if err != nil {
+		return "", err
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return "", wrongType(key, value, "string")
+	}
+
+	return text, nil
+}
+
+// Int accepts any whole number, formats disagree on what type numbers are
+func (values FrontmatterValueType) Int(key string) (int, error) {
+	value, err := values.get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	switch number := value.(type) {
+	case int:
+		return number, nil
+	case int64:
+		return int(number), nil
+	case uint64:
+		return int(number), nil
+	case float64:
+		if number == math.Trunc(number) {
+			return int(number), nil
+		}
+	}
+
+	return 0, wrongType(key, value, "whole number")
+}
+
+func (values FrontmatterValueType) Bool(key string) (bool, error) {
+	value, err := values.get(key)
+	if err != nil {
+		return false, err
+	}
+
+	boolean, ok := value.(bool)
+	if !ok {
+		return false, wrongType(key, value, "boolean")
+	}
+
+	return boolean, nil
+}
+
+// Time accepts dates already parsed by the format or strings in one of DateFormats
+func (values FrontmatterValueType) Time(key string) (time.Time, error) {
+	value, err := values.get(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch date := value.(type) {
+	case time.Time:
+		return date, nil
+	case string:
+		for _, format := range DateFormats {
+			if parsed, err := time.Parse(format, date); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+
+	return time.Time{}, wrongType(key, value, "date")
+}
+
+// StringSlice accepts a list of strings or a single string
+func (values FrontmatterValueType) StringSlice(key string) ([]string, error) {
+	value, err := values.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch list := value.(type) {
+	case string:
+		return []string{list}, nil
+	case []string:
+		return list, nil
+	case []interface{}:
+		texts := make([]string, len(list))
+
+		for i, item := range list {
+			text, ok := item.(string)
+			if !ok {
+				return nil, wrongType(fmt.Sprintf("%v[%v]", key, i), item, "string")
+			}
+
+			texts[i] = text
+		}
+
+		return texts, nil
+	}
+
+	return nil, wrongType(key, value, "list of strings")
+}
+
+func (values FrontmatterValueType) Map(key string) (FrontmatterValueType, error) {
+	value, err := values.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, ok := value.(FrontmatterValueType)
+	if !ok {
+		return nil, wrongType(key, value, "map")
+	}
+
+	return inner, nil
+}
+
+// keeps only the values which could have come from a file, plugins can add anything to frontmatter
+// including values which refer back to it
+func plainFrontmatter(value interface{}) (interface{}, bool) {
+	switch typed := value.(type) {
+	case nil, bool, string, int, int64, uint64, float64, time.Time:
+		return value, true
+	case FrontmatterValueType:
+		values := make(FrontmatterValueType, len(typed))
+		for key, inner := range typed {
+			if plain, ok := plainFrontmatter(inner); ok {
+				values[key] = plain
+			}
+		}
+		return values, true
+	case []interface{}:
+		list := []interface{}{}
+		for _, inner := range typed {
+			if plain, ok := plainFrontmatter(inner); ok {
+				list = append(list, plain)
+			}
+		}
+		return list, true
+	}
+
+	return nil, false
+}
+
+// Decode fills the struct pointed to by out from the frontmatter, fields are matched like YAML would
+// so they can be renamed with yaml struct tags
+func (values FrontmatterValueType) Decode(out interface{}) error {
+	plain, _ := plainFrontmatter(values)
+	encoded, err := yaml.Marshal(plain)
+
+	if err != nil {
+		return errors.Wrap(err, "Could not encode frontmatter")
+	}
+
+	return errors.Wrap(yaml.Unmarshal(encoded, out), "Could not decode frontmatter")
+}
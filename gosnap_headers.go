@@ -0,0 +1,171 @@
+package gosnap
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// headers a file starts out with, the Content-Type from its path and anything under "headers" in its frontmatter,
+// for example
+//
+//	headers:
+//	  Cache-Control: max-age=3600
+//	  Link: [</style.css>; rel=preload, </app.js>; rel=preload]
+func newHeaders(filePath string, frontmatterValues FrontmatterValueType) (http.Header, error) {
+	header := http.Header{}
+
+	if contentType := mime.TypeByExtension(filepath.Ext(filePath)); contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	if !frontmatterValues.Has("headers") {
+		return header, nil
+	}
+
+	values, err := frontmatterValues.Map("headers")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range values {
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+
+		header.Del(key)
+		for _, item := range list {
+			text, err := headerValue(key, item)
+
+			if err == nil {
+				err = CheckHeader(key, text)
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "Could not read headers")
+			}
+
+			header.Add(key, text)
+		}
+	}
+
+	return header, nil
+}
+
+// headers are text, but numbers and the like are fine too since formats parse them as something else
+func headerValue(key string, value interface{}) (string, error) {
+	switch value.(type) {
+	case FrontmatterValueType, []interface{}, nil:
+		return "", wrongType(key, value, "header value")
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+// characters other than letters and digits which RFC 7230 allows in header names
+const headerNameSymbols = "!#$%&'*+-.^_`|~"
+
+// CheckHeader errors for a header which can not be sent as it is, since its name is not a valid token or its
+// value has a line break. Either could otherwise end up as extra lines in a response or in server configuration.
+func CheckHeader(key string, value string) error {
+	if key == "" {
+		return errors.New("Header name can not be empty")
+	}
+
+	for _, character := range key {
+		if !('a' <= character && character <= 'z' || 'A' <= character && character <= 'Z' || '0' <= character && character <= '9' || strings.ContainsRune(headerNameSymbols, character)) {
+			return errors.Errorf("Header name %q can only have letters, digits and %v", key, headerNameSymbols)
+		}
+	}
+
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.Errorf("Header %v can not have a line break in its value %q", key, value)
+	}
+
+	return nil
+}
+
+func copyHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+
+	copied := make(http.Header, len(header))
+
+	for key, values := range header {
+		copied[key] = append([]string{}, values...)
+	}
+
+	return copied
+}
+
+// Headers are the HTTP response headers of the file, plugins can change them and the changes are kept.
+// Content-Length and Content-MD5 are brought up to date with Content after every plugin, not while it runs.
+func (gsf *GoSnapFile) Headers() http.Header {
+	if gsf.header == nil {
+		gsf.header = http.Header{}
+	}
+
+	return gsf.header
+}
+
+// identifies Content by its MD5 sum, so that changes are noticed however a plugin made them
+type contentState struct {
+	sum    [md5.Size]byte
+	length int
+}
+
+// passthrough files have no content to sum, their state never changes
+var passthroughState = contentState{length: -1}
+
+func stateOf(gsf *GoSnapFile) contentState {
+	if gsf.Passthrough {
+		return passthroughState
+	}
+
+	return contentState{sum: md5.Sum(gsf.Content), length: len(gsf.Content)}
+}
+
+// sets Content-Length and Content-MD5 from the state Content was last synced in
+func (gsf *GoSnapFile) syncHeaders() {
+	header := gsf.Headers()
+
+	if gsf.Passthrough {
+		if gsf.FileInfo != nil {
+			header.Set("Content-Length", strconv.FormatInt(gsf.FileInfo.Size(), 10))
+		}
+
+		return
+	}
+
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(gsf.synced.sum[:]))
+	header.Set("Content-Length", strconv.Itoa(gsf.synced.length))
+}
+
+// brings the FileInfo and headers of the file up to date with its Content and where it is in the file map,
+// returning whether Content changed since the last time. Files which were never synced count as changed.
+func (gsf *GoSnapFile) sync(filePath string) bool {
+	state := stateOf(gsf)
+	changed := state != gsf.synced
+	gsf.synced = state
+
+	gsf.syncFileInfo(filePath)
+	gsf.syncHeaders()
+
+	return changed
+}
+
+// syncs files which never were, so that a plugin about to run is only held responsible for its own changes
+func (gsf *GoSnapFile) syncFirst(filePath string) {
+	if gsf.synced == (contentState{}) {
+		gsf.sync(filePath)
+	}
+}
@@ -0,0 +1,178 @@
+package gosnap
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// file in Source with gitignore style patterns that are added to IgnorePatterns, it is never written out
+const IGNORE_FILE = ".gosnapignore"
+
+// one gitignore style pattern turned into a regular expression on local paths
+type ignoreRule struct {
+	expression    *regexp.Regexp
+	negate        bool
+	directoryOnly bool
+}
+
+// translates the glob part of a pattern, ** only means something special as a whole path segment
+func globToExpression(glob string) string {
+	var expression bytes.Buffer
+
+	for i := 0; i < len(glob); i++ {
+		switch character := glob[i]; character {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/') {
+				expression.WriteString("(?:.*/)?")
+				i += 2
+			} else if glob[i:] == "**" && (i == 0 || glob[i-1] == '/') {
+				expression.WriteString(".*")
+				i++
+			} else {
+				expression.WriteString("[^/]*")
+			}
+		case '?':
+			expression.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			expression.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(character)))
+		}
+	}
+
+	return expression.String()
+}
+
+// parses a line like gitignore does, blank lines and comments give no rule
+func parseIgnoreRule(line string) (*ignoreRule, error) {
+	pattern := strings.TrimRight(line, " \t\r")
+
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return nil, nil
+	}
+
+	rule := &ignoreRule{}
+
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.directoryOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	// patterns with a slash are relative to Source, without one they match a name anywhere
+	prefix := "^(?:.*/)?"
+	if strings.Contains(pattern, "/") {
+		prefix = "^"
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+
+	expression, err := regexp.Compile(prefix + globToExpression(pattern) + "$")
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not parse ignore pattern %v", line)
+	}
+
+	rule.expression = expression
+
+	return rule, nil
+}
+
+// IgnorePattern adds gitignore style patterns such as "node_modules/", "*.swp", "_drafts/**" or "!keep.md"
+func (gs *GoSnap) IgnorePattern(patterns ...string) {
+	gs.IgnorePatterns = append(gs.IgnorePatterns, patterns...)
+}
+
+func (gs *GoSnap) ignoreFile() string {
+	return filepath.Join(gs.Source, IGNORE_FILE)
+}
+
+// IgnorePatterns followed by the lines of the ignore file in Source, read again for every walk so that Watch
+// notices when it changes
+func (gs *GoSnap) ignoreRules() ([]*ignoreRule, error) {
+	lines := append([]string{}, gs.IgnorePatterns...)
+
+	if _, err := osStat(gs.ignoreFile()); err == nil {
+		content, err := ioUtilReadFile(gs.ignoreFile())
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not read ignore file %v", gs.ignoreFile())
+		}
+
+		lines = append(lines, strings.Split(string(content), "\n")...)
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "Could not check for ignore file %v", gs.ignoreFile())
+	}
+
+	rules := []*ignoreRule{}
+
+	for _, line := range lines {
+		rule, err := parseIgnoreRule(line)
+
+		if err != nil {
+			return nil, err
+		}
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// whether a walked path is ignored, the last pattern which matches decides like in gitignore. Paths matching
+// IgnoreExpressions or the exact paths in IgnoreMap are always ignored.
+func (gs *GoSnap) isIgnored(rules []*ignoreRule, filePath string, isDir bool) bool {
+	if _, ignored := gs.IgnoreMap[filePath]; ignored {
+		return true
+	}
+
+	internalPath := filepath.ToSlash(TransformToLocalPath(filePath, gs.Source))
+	if internalPath == "" || internalPath == "." {
+		return false
+	}
+
+	for _, expression := range gs.IgnoreExpressions {
+		if expression.MatchString(internalPath) {
+			return true
+		}
+	}
+
+	ignored := false
+
+	for _, rule := range rules {
+		if rule.directoryOnly && !isDir {
+			continue
+		}
+		if rule.expression.MatchString(internalPath) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
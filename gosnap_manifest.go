@@ -0,0 +1,117 @@
+package gosnap
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Description of one file written by a build
+type ManifestEntry struct {
+	Path        string   `json:"path"`
+	Size        int64    `json:"size"`
+	MD5         string   `json:"md5"`
+	ContentType string   `json:"contentType"`
+	SourcePath  string   `json:"sourcePath,omitempty"`
+	Mode        string   `json:"mode"`
+	ModifiedBy  []string `json:"modifiedBy,omitempty"`
+}
+
+var osOpen = os.Open
+
+// passthrough files are hashed from their source a bit at a time
+func passthroughSum(file *GoSnapFile) (int64, string, error) {
+	source, err := osOpen(file.SourcePath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer source.Close()
+
+	hash := md5.New()
+	size, err := io.Copy(hash, source)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileMode(file *GoSnapFile) os.FileMode {
+	if file.FileInfo != nil {
+		return file.FileInfo.Mode()
+	}
+
+	return DEFAULT_PERM
+}
+
+// BuildManifest describes every file in fileMap as it would be written out, sorted by path
+func BuildManifest(fileMap FileMapType) ([]ManifestEntry, error) {
+	entries := make([]ManifestEntry, 0, len(fileMap))
+
+	for filePath, file := range fileMap {
+		entry := ManifestEntry{
+			Path:       filePath,
+			SourcePath: file.SourcePath,
+			Mode:       fmt.Sprintf("%#o", fileMode(file).Perm()),
+			ModifiedBy: file.ModifiedBy,
+		}
+
+		entry.ContentType = file.Headers().Get("Content-Type")
+		if entry.ContentType == "" {
+			entry.ContentType = mime.TypeByExtension(path.Ext(filePath))
+		}
+
+		if file.Passthrough {
+			size, sum, err := passthroughSum(file)
+
+			if err != nil {
+				return nil, errors.Wrapf(err, "Could not hash %v", file.SourcePath)
+			}
+
+			entry.Size = size
+			entry.MD5 = sum
+		} else {
+			sum := md5.Sum(file.Content)
+
+			entry.Size = int64(len(file.Content))
+			entry.MD5 = hex.EncodeToString(sum[:])
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return entries, nil
+}
+
+// writes the manifest for FileMap to Manifest inside of Destination
+func (gs *GoSnap) writeManifest() error {
+	entries, err := BuildManifest(gs.FileMap)
+
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(entries, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Could not encode manifest")
+	}
+
+	finalPath := path.Join(gs.Destination, gs.Manifest)
+
+	if err := mkdirAll(path.Dir(finalPath), os.ModePerm); err != nil {
+		return errors.Wrapf(err, "Could not create required directories for %v", finalPath)
+	}
+
+	return ioUtilWriteFile(finalPath, append(content, '\n'), DEFAULT_PERM)
+}
@@ -0,0 +1,200 @@
+package gosnap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// unmarshal functions for structured files by extension
+var structuredFormats = map[string]func([]byte, interface{}) error{
+	".yaml": yaml.Unmarshal,
+	".yml":  yaml.Unmarshal,
+	".json": json.Unmarshal,
+	".toml": toml.Unmarshal,
+}
+
+// parses a YAML, JSON or TOML file into values shaped like frontmatter, though it does not have to be a map
+func parseStructured(filePath string, data []byte) (interface{}, error) {
+	unmarshal, known := structuredFormats[strings.ToLower(filepath.Ext(filePath))]
+
+	if !known {
+		return nil, errors.Errorf("Unknown format for %v, expected YAML, JSON or TOML", filePath)
+	}
+
+	var raw interface{}
+
+	if err := unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrapf(err, "Could not parse %v", filePath)
+	}
+
+	return normalizeFrontmatter(raw), nil
+}
+
+// parses a CSV file into a list with a map per row, keyed by the column names in the first row
+func parseCSV(filePath string, data []byte) (interface{}, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not parse %v", filePath)
+	}
+
+	records := []interface{}{}
+
+	if len(rows) == 0 {
+		return records, nil
+	}
+
+	for _, row := range rows[1:] {
+		record := make(FrontmatterValueType, len(rows[0]))
+
+		for i, column := range rows[0] {
+			if i < len(row) {
+				record[column] = row[i]
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func parseDataFile(filePath string, data []byte) (interface{}, error) {
+	if strings.ToLower(filepath.Ext(filePath)) == ".csv" {
+		return parseCSV(filePath, data)
+	}
+
+	return parseStructured(filePath, data)
+}
+
+// data files are the files in DataDirectory in one of the formats that can be parsed, anything else in there
+// like a README is treated as a normal file
+func (gs *GoSnap) isDataFile(internalPath string) bool {
+	if gs.DataDirectory == "" || !strings.HasPrefix(internalPath, path.Clean(gs.DataDirectory)+"/") {
+		return false
+	}
+
+	extension := strings.ToLower(path.Ext(internalPath))
+	_, structured := structuredFormats[extension]
+
+	return structured || extension == ".csv"
+}
+
+// nests the contents of data files by their path inside of DataDirectory, without extensions
+func dataTree(dataDirectory string, data map[string]interface{}) FrontmatterValueType {
+	tree := make(FrontmatterValueType)
+
+	// in order so that a directory and a file with the same name always end up the same way
+	internalPaths := make([]string, 0, len(data))
+	for internalPath := range data {
+		internalPaths = append(internalPaths, internalPath)
+	}
+	sort.Strings(internalPaths)
+
+	for _, internalPath := range internalPaths {
+		relative := strings.TrimPrefix(internalPath, path.Clean(dataDirectory)+"/")
+		parts := strings.Split(strings.TrimSuffix(relative, path.Ext(relative)), "/")
+
+		branch := tree
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := branch[part].(FrontmatterValueType)
+			if !ok {
+				next = make(FrontmatterValueType)
+				branch[part] = next
+			}
+
+			branch = next
+		}
+
+		branch[parts[len(parts)-1]] = data[internalPath]
+	}
+
+	return tree
+}
+
+// Metadata combined with the contents of MetadataFile, which win when both have the same key
+func (gs *GoSnap) siteMetadata() (FrontmatterValueType, error) {
+	site := gs.Metadata.Copy()
+	if site == nil {
+		site = make(FrontmatterValueType)
+	}
+
+	if gs.MetadataFile == "" {
+		return site, nil
+	}
+
+	data, err := ioUtilReadFile(gs.MetadataFile)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not read metadata file %v", gs.MetadataFile)
+	}
+
+	parsed, err := parseStructured(gs.MetadataFile, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := parsed.(FrontmatterValueType)
+	if !ok {
+		return nil, errors.Errorf("Metadata file %v must be a map of keys to values", gs.MetadataFile)
+	}
+
+	for key, value := range values {
+		site[key] = value
+	}
+
+	return site, nil
+}
+
+// gives every file the site metadata as "site" in its frontmatter, the same map is shared by all of them
+// so that plugins can change it for everyone. A file with a "site" of its own would hide the metadata from
+// its templates, so that is an error.
+func (gs *GoSnap) attachMetadata(fileMap FileMapType, data map[string]interface{}) error {
+	site, err := gs.siteMetadata()
+
+	if err != nil {
+		return err
+	}
+
+	if gs.DataDirectory != "" {
+		site["data"] = dataTree(gs.DataDirectory, data)
+	}
+
+	for filePath, file := range fileMap {
+		if file.Data.Has("site") {
+			return errors.Errorf("Frontmatter of %v can not have site since that is where the site metadata goes", filePath)
+		}
+	}
+
+	gs.site = site
+	gs.shareMetadata(fileMap)
+
+	return nil
+}
+
+// gives the site metadata to files which do not have it yet, like the ones plugins add
+func (gs *GoSnap) shareMetadata(fileMap FileMapType) {
+	if gs.site == nil {
+		return
+	}
+
+	for _, file := range fileMap {
+		if file.Data == nil {
+			file.Data = make(FrontmatterValueType)
+		}
+
+		if !file.Data.Has("site") {
+			file.Data["site"] = gs.site
+		}
+	}
+}
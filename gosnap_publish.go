@@ -0,0 +1,58 @@
+package gosnap
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var timeNow = time.Now
+
+// whether a file should be built yet, going by "draft" and "publishDate" in its frontmatter
+func (gs *GoSnap) published(file *GoSnapFile, now time.Time) (bool, time.Time, error) {
+	draft, err := file.Data.Bool("draft")
+
+	if err != nil && errors.Cause(err) != ErrMissingKey {
+		return false, time.Time{}, err
+	}
+	if draft && !gs.Drafts {
+		return false, time.Time{}, nil
+	}
+
+	publishDate, err := file.Data.Time("publishDate")
+
+	if err != nil && errors.Cause(err) != ErrMissingKey {
+		return false, time.Time{}, err
+	}
+	if err == nil && publishDate.After(now) {
+		return false, publishDate, nil
+	}
+
+	return true, time.Time{}, nil
+}
+
+// removes drafts, unless Drafts is set, and files with a publishDate after now from fileMap.
+// Returns when the first of the removed files is due to be published, or the zero time if none are.
+func (gs *GoSnap) removeUnpublished(fileMap FileMapType, now time.Time) (time.Time, error) {
+	next := time.Time{}
+
+	for filePath, file := range fileMap {
+		published, due, err := gs.published(file, now)
+
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "Could not tell if %v is published", filePath)
+		}
+		if published {
+			continue
+		}
+
+		gs.Printf("holding back unpublished file %v", filePath)
+		delete(fileMap, filePath)
+
+		if !due.IsZero() && (next.IsZero() || due.Before(next)) {
+			next = due
+		}
+	}
+
+	return next, nil
+}
@@ -2,24 +2,20 @@ package gosnap
 
 import (
 	"bytes"
-	"crypto/md5"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"mime"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
 
-type FrontmatterValueType map[interface{}]interface{}
-
-type HeaderGetter func() http.Header
-
 // utility functions for reading
 func TransformToLocalPath(filePath string, source string) string {
 	filePath = path.Clean(filePath)
@@ -32,40 +28,157 @@ func TransformToLocalPath(filePath string, source string) string {
 	return internalPath
 }
 
-func parseFrontmatter(data []byte) ([]byte, FrontmatterValueType, error) {
-	if bytes.HasPrefix(data, []byte("---\n")) {
-		splits := bytes.SplitN(data, []byte("\n---\n"), 2)
+// FrontmatterParser recognizes one format of frontmatter at the start of a file. If data does not start with
+// its format it returns matched false, otherwise the rest of the file and the parsed values.
+type FrontmatterParser func(filePath string, data []byte) (content []byte, values FrontmatterValueType, matched bool, err error)
+
+// makes parsed values look the same no matter which format they came from
+func normalizeFrontmatter(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		values := make(FrontmatterValueType, len(typed))
+		for key, inner := range typed {
+			values[fmt.Sprint(key)] = normalizeFrontmatter(inner)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(FrontmatterValueType, len(typed))
+		for key, inner := range typed {
+			values[key] = normalizeFrontmatter(inner)
+		}
+		return values
+	case []map[string]interface{}:
+		list := make([]interface{}, len(typed))
+		for i, inner := range typed {
+			list[i] = normalizeFrontmatter(inner)
+		}
+		return list
+	case []interface{}:
+		list := make([]interface{}, len(typed))
+		for i, inner := range typed {
+			list[i] = normalizeFrontmatter(inner)
+		}
+		return list
+	}
+
+	return value
+}
+
+// turns whatever a format unmarshalled into frontmatter values, nothing at all counts as empty frontmatter
+func toFrontmatter(raw interface{}) (FrontmatterValueType, error) {
+	if raw == nil {
+		return make(FrontmatterValueType), nil
+	}
+
+	values, ok := normalizeFrontmatter(raw).(FrontmatterValueType)
+	if !ok {
+		return nil, errors.New("Frontmatter must be a map of keys to values")
+	}
+
+	return values, nil
+}
+
+// DelimitedFrontmatter parses frontmatter surrounded by lines containing only delimiter
+func DelimitedFrontmatter(name string, delimiter string, unmarshal func([]byte, interface{}) error) FrontmatterParser {
+	opening := []byte(delimiter + "\n")
+	closing := []byte("\n" + delimiter + "\n")
+
+	return func(filePath string, data []byte) ([]byte, FrontmatterValueType, bool, error) {
+		if !bytes.HasPrefix(data, opening) {
+			return nil, nil, false, nil
+		}
+
+		rest := data[len(opening):]
+
+		// frontmatter with nothing in it
+		if bytes.HasPrefix(rest, opening) {
+			return rest[len(opening):], make(FrontmatterValueType), true, nil
+		}
+
+		splits := bytes.SplitN(rest, closing, 2)
 
 		if len(splits) != 2 {
-			return nil, nil, errors.New("Incorrect format for file. If file includes frontmatter it must start with it and surround it with lines containing only ---")
+			return nil, nil, true, errors.Errorf("Incorrect format for file. If file includes frontmatter it must start with it and surround it with lines containing only %v", delimiter)
 		}
 
-		frontmatterValues := make(FrontmatterValueType)
+		var raw interface{}
 
-		if err := yaml.Unmarshal(splits[0], frontmatterValues); err != nil {
-			return nil, nil, errors.Wrap(err, "Could not parse front matter YAML")
+		if err := unmarshal(splits[0], &raw); err != nil {
+			return nil, nil, true, errors.Wrapf(err, "Could not parse front matter %v", name)
 		}
 
-		return splits[1], frontmatterValues, nil
-	} else {
-		return data, nil, nil
+		values, err := toFrontmatter(raw)
+		if err != nil {
+			return nil, nil, true, errors.Wrapf(err, "Could not parse front matter %v", name)
+		}
+
+		return splits[1], values, true, nil
 	}
 }
 
-func parseHeaders(filePath string, contents []byte, frontmatterValues FrontmatterValueType) HeaderGetter {
-	return func() http.Header {
-		sum := md5.Sum(contents)
+// files which JSONFrontmatter looks at, plenty of other files like .webmanifest or .js.map are JSON themselves
+var JSONFrontmatterExtensions = []string{".md", ".markdown", ".html", ".htm"}
+
+func hasJSONFrontmatterExtension(filePath string) bool {
+	extension := strings.ToLower(filepath.Ext(filePath))
 
-		return http.Header{
-			// these are response headers that seem relevant to static files
-			"Content-Encoding": []string{},
-			"Content-Language": []string{},
-			"Content-Length":   []string{},
-			"Content-MD5":      []string{string(sum[:])},
-			"Content-Type":     []string{mime.TypeByExtension(filepath.Ext(filePath))},
-			"Last-Modified":    []string{},
+	for _, candidate := range JSONFrontmatterExtensions {
+		if extension == candidate {
+			return true
 		}
 	}
+
+	return false
+}
+
+// JSONFrontmatter parses a JSON object at the very start of a file with one of JSONFrontmatterExtensions,
+// files starting with {{ are templates rather than JSON
+func JSONFrontmatter(filePath string, data []byte) ([]byte, FrontmatterValueType, bool, error) {
+	if !bytes.HasPrefix(data, []byte("{")) || bytes.HasPrefix(data, []byte("{{")) || !hasJSONFrontmatterExtension(filePath) {
+		return nil, nil, false, nil
+	}
+
+	reader := bytes.NewReader(data)
+	decoder := json.NewDecoder(reader)
+	var raw map[string]interface{}
+
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, nil, true, errors.Wrap(err, "Could not parse front matter JSON")
+	}
+
+	// what the decoder read ahead and what it did not get to yet together are the rest of the file
+	rest, _ := ioutil.ReadAll(decoder.Buffered())
+	rest = append(rest, data[len(data)-reader.Len():]...)
+
+	values, err := toFrontmatter(raw)
+	if err != nil {
+		return nil, nil, true, errors.Wrap(err, "Could not parse front matter JSON")
+	}
+
+	return bytes.TrimPrefix(rest, []byte("\n")), values, true, nil
+}
+
+var frontmatterParsers = []FrontmatterParser{
+	DelimitedFrontmatter("YAML", "---", yaml.Unmarshal),
+	DelimitedFrontmatter("TOML", "+++", toml.Unmarshal),
+	JSONFrontmatter,
+}
+
+// RegisterFrontmatter adds a format of frontmatter to try on every file after the built in YAML, TOML and JSON
+func RegisterFrontmatter(parser FrontmatterParser) {
+	frontmatterParsers = append(frontmatterParsers, parser)
+}
+
+func parseFrontmatter(filePath string, data []byte) ([]byte, FrontmatterValueType, error) {
+	for _, parser := range frontmatterParsers {
+		content, values, matched, err := parser(filePath, data)
+
+		if matched {
+			return content, values, err
+		}
+	}
+
+	return data, nil, nil
 }
 
 var ioUtilReadFile = ioutil.ReadFile
@@ -77,18 +190,44 @@ func (gs *GoSnap) ReadFile(path string) (*GoSnapFile, error) {
 		return &GoSnapFile{}, errors.Wrap(err, "Could not read file from filesystem")
 	}
 
-	content, frontmatterValues, yamlErr := parseFrontmatter(data)
-	if yamlErr != nil {
-		return &GoSnapFile{}, errors.Wrapf(yamlErr, "Error parsing YAML in %v", path)
+	content, frontmatterValues, frontmatterErr := parseFrontmatter(path, data)
+	if frontmatterErr != nil {
+		return &GoSnapFile{}, errors.Wrapf(frontmatterErr, "Error parsing frontmatter in %v", path)
 	}
 
-	headers := parseHeaders(path, content, frontmatterValues)
+	header, err := newHeaders(path, frontmatterValues)
+	if err != nil {
+		return &GoSnapFile{}, errors.Wrapf(err, "Error parsing frontmatter in %v", path)
+	}
+
+	return &GoSnapFile{Content: content, Data: frontmatterValues, header: header, SourcePath: path}, nil
+}
+
+// common large files which rarely need processing, for use as GoSnap.Passthrough
+var BinaryPatterns = []string{
+	"*.png", "*.jpg", "*.jpeg", "*.gif", "*.webp", "*.ico",
+	"*.mp4", "*.webm", "*.mov", "*.mp3", "*.ogg", "*.wav",
+	"*.zip", "*.gz", "*.tar", "*.tgz", "*.pdf",
+	"*.woff", "*.woff2", "*.ttf", "*.otf", "*.eot",
+}
+
+// passthrough patterns match either the whole local path or just the file name
+func (gs *GoSnap) isPassthrough(internalPath string) bool {
+	for _, pattern := range gs.Passthrough {
+		if matched, _ := path.Match(pattern, internalPath); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(internalPath)); matched {
+			return true
+		}
+	}
 
-	return &GoSnapFile{Content: content, Data: frontmatterValues, Headers: headers}, nil
+	return false
 }
 
 var filepathWalk = filepath.Walk
 
+// Ignore skips exactly the walked path ignore, see IgnorePattern for anything more
 func (gs *GoSnap) Ignore(ignore string) {
 	if gs.IgnoreMap == nil {
 		gs.IgnoreMap = make(StringSet)
@@ -103,36 +242,118 @@ func (gs *GoSnap) IgnoreAll(ignore ...string) {
 	}
 }
 
-func (gs *GoSnap) Read() error {
+// reads a single walked file and stores it in fileMap under its local path,
+// or if it is a data file stores what it contains in data instead
+func (gs *GoSnap) readInto(fileMap FileMapType, data map[string]interface{}, filePath string, fileInfo os.FileInfo) error {
+	internalPath := TransformToLocalPath(filePath, gs.Source)
+
+	if gs.isDataFile(internalPath) {
+		content, err := ioUtilReadFile(filePath)
+
+		if err != nil {
+			return errors.Wrapf(err, "Could not read data file %v", filePath)
+		}
+
+		value, err := parseDataFile(filePath, content)
+
+		if err != nil {
+			return err
+		}
+
+		data[internalPath] = value
+
+		return nil
+	}
+
+	var file *GoSnapFile
+
+	if gs.isPassthrough(internalPath) {
+		header, _ := newHeaders(filePath, nil)
+		file = &GoSnapFile{header: header, SourcePath: filePath, Passthrough: true}
+	} else {
+		var err error
+		file, err = gs.ReadFile(filePath)
+
+		if err != nil {
+			return errors.Wrapf(err, "Could not read file %v", filePath)
+		}
+	}
+
+	file.FileInfo = fileInfo
+	file.sync(internalPath)
+	// an HTTP-date like "Mon, 02 Jan 2006 15:04:05 GMT" which http.ParseTime can read back
+	file.Headers().Set("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+	fileMap[internalPath] = file
+
+	return nil
+}
+
+// walks Source and calls visit for every file which is not ignored
+func (gs *GoSnap) walkSource(visit func(string, os.FileInfo) error) error {
 	if gs.Source == "" {
 		return errors.New("No Source set in GoSnap object")
 	}
 
-	// start over fresh for each build
-	gs.FileMap = make(FileMapType)
+	rules, err := gs.ignoreRules()
 
-	readVisitor := func(filePath string, fileInfo os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+
+	walkVisitor := func(filePath string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
 			return errors.Wrapf(err, "Filesystem walk error at %v", filePath)
 		}
 
-		if _, ignored := gs.IgnoreMap[filePath]; !ignored && fileInfo != nil && !fileInfo.IsDir() {
-			internalPath := TransformToLocalPath(filePath, gs.Source)
-
-			file, err := gs.ReadFile(filePath)
+		// the metadata file is for the whole site, not a page of it
+		if gs.MetadataFile != "" && filepath.Clean(filePath) == filepath.Clean(gs.MetadataFile) {
+			return nil
+		}
+		if filepath.Clean(filePath) == gs.ignoreFile() || fileInfo == nil {
+			return nil
+		}
 
-			if err != nil {
-				return errors.Wrapf(err, "Could not read file %v", filePath)
+		if gs.isIgnored(rules, filePath, fileInfo.IsDir()) {
+			// nothing inside of an ignored directory is looked at, not even to be included again
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
 			}
 
-			file.FileInfo = fileInfo
-			// Format example: "Mon, 02 Jan 2006 15:04:05 MST"
-			file.Headers().Set("Last-Modified", fileInfo.ModTime().Format(time.RFC1123))
-			gs.FileMap[internalPath] = file
+			return nil
+		}
+
+		if !fileInfo.IsDir() {
+			return visit(filePath, fileInfo)
 		}
 
 		return nil
 	}
 
-	return filepathWalk(gs.Source, readVisitor)
+	return filepathWalk(gs.Source, walkVisitor)
+}
+
+func (gs *GoSnap) Read() error {
+	if gs.Source == "" {
+		return errors.New("No Source set in GoSnap object")
+	}
+
+	// start over fresh for each build
+	gs.FileMap = make(FileMapType)
+	data := make(map[string]interface{})
+
+	err := gs.walkSource(func(filePath string, fileInfo os.FileInfo) error {
+		return gs.readInto(gs.FileMap, data, filePath, fileInfo)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := gs.attachMetadata(gs.FileMap, data); err != nil {
+		return err
+	}
+
+	_, err = gs.removeUnpublished(gs.FileMap, timeNow())
+
+	return err
 }
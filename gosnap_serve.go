@@ -0,0 +1,101 @@
+package gosnap
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var httpListenAndServe = http.ListenAndServe
+
+// find the file for a request path, directories are served by their index.html
+func (fileMap FileMapType) lookup(requestPath string) (string, *GoSnapFile) {
+	filePath := strings.TrimPrefix(path.Clean("/"+requestPath), "/")
+
+	candidates := []string{filePath, path.Join(filePath, "index.html")}
+	if strings.HasSuffix(requestPath, "/") {
+		candidates = candidates[1:]
+	}
+
+	for _, candidate := range candidates {
+		if file, exists := fileMap[candidate]; exists {
+			return candidate, file
+		}
+	}
+
+	return "", nil
+}
+
+// Handler serves FileMap straight from memory using the headers of each file as response headers
+func (gs *GoSnap) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filePath, file := gs.FileMap.lookup(r.URL.Path)
+
+		if file == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		header := w.Header()
+
+		// requests are served concurrently so the headers are only read, Headers would create them
+		for key, values := range file.header {
+			for _, value := range values {
+				if value != "" {
+					header.Add(key, value)
+				}
+			}
+		}
+
+		var content io.ReadSeeker = bytes.NewReader(file.Content)
+
+		if file.Passthrough {
+			source, err := os.Open(file.SourcePath)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer source.Close()
+
+			content = source
+		}
+
+		modTime := time.Time{}
+		if lastModified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+			modTime = lastModified
+		} else if file.FileInfo != nil {
+			modTime = file.FileInfo.ModTime()
+		}
+
+		http.ServeContent(w, r, filePath, modTime, content)
+	})
+}
+
+// Serve reads Source, runs the plugins and serves the result from memory on addr instead of writing it out
+func (gs *GoSnap) Serve(addr string) error {
+	gs.Print("read all files into map")
+	err := gs.Read()
+	gs.Printf("read %v files", len(gs.FileMap))
+
+	if err != nil {
+		return errors.Wrap(err, "Serve failed at read step")
+	}
+
+	gs.Print("run files through plugins")
+	err = Run(gs.FileMap, gs.Plugins)
+
+	if err != nil {
+		return errors.Wrap(err, "Serve failed during plugin run")
+	}
+
+	gs.Printf("serving %v files on %v", len(gs.FileMap), addr)
+
+	return errors.Wrapf(httpListenAndServe(addr, gs.Handler()), "Could not serve on %v", addr)
+}
@@ -1,14 +1,28 @@
 package gosnap
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 // shortcut to get a valid FileInfo value
@@ -77,6 +91,17 @@ var readFileTests = []readFileStruct{
 	{"bile-arrays-other-format.html", []byte("---\nkey:\n - value\n---\nblah\nblah"), []byte("blah\nblah"), FrontmatterValueType{"key": []interface{}{"value"}}, nil},
 	{"bile-maps.html", []byte("---\nkey:\n inner: value\n---\nblah\nblah"), []byte("blah\nblah"), FrontmatterValueType{"key": FrontmatterValueType{"inner": "value"}}, nil},
 	{"bile-array-maps.html", []byte("---\nkey:\n - inner: value\n---\nblah\nblah"), []byte("blah\nblah"), FrontmatterValueType{"key": []interface{}{FrontmatterValueType{"inner": "value"}}}, nil},
+	{"empty-yaml.html", []byte("---\n---\nblah"), []byte("blah"), FrontmatterValueType{}, nil},
+	{"toml.html", []byte("+++\nkey = \"value\"\n+++\nblah\nblah"), []byte("blah\nblah"), FrontmatterValueType{"key": "value"}, nil},
+	{"toml-maps.html", []byte("+++\n[key]\ninner = \"value\"\n+++\nblah"), []byte("blah"), FrontmatterValueType{"key": FrontmatterValueType{"inner": "value"}}, nil},
+	{"toml-array-maps.html", []byte("+++\n[[key]]\ninner = \"value\"\n+++\nblah"), []byte("blah"), FrontmatterValueType{"key": []interface{}{FrontmatterValueType{"inner": "value"}}}, nil},
+	{"json.html", []byte("{\"key\": \"value\", \"list\": [\"a\"]}\nblah\nblah"), []byte("blah\nblah"), FrontmatterValueType{"key": "value", "list": []interface{}{"a"}}, nil},
+	{"json-maps.html", []byte("{\"key\": {\"inner\": \"value\"}}\nblah"), []byte("blah"), FrontmatterValueType{"key": FrontmatterValueType{"inner": "value"}}, nil},
+	{"data.json", []byte("{\"key\": \"value\"}\n"), []byte("{\"key\": \"value\"}\n"), nil, nil},
+	{"site.webmanifest", []byte("{\"name\": \"site\"}\n"), []byte("{\"name\": \"site\"}\n"), nil, nil},
+	{"app.js.map", []byte("{\"version\": 3}"), []byte("{\"version\": 3}"), nil, nil},
+	{"list.html", []byte("{{#each posts}}{{title}}{{/each}}"), []byte("{{#each posts}}{{title}}{{/each}}"), nil, nil},
+	{"list.hbs", []byte("{{#each posts}}{{title}}{{/each}}"), []byte("{{#each posts}}{{title}}{{/each}}"), nil, nil},
 }
 
 func TestReadFile(t *testing.T) {
@@ -269,6 +294,88 @@ func TestRead(t *testing.T) {
 	}
 }
 
+type passthroughStruct struct {
+	patterns      []string
+	expectedReads []string
+	passthrough   []string
+}
+
+var passthroughTests = []passthroughStruct{
+	{nil, []string{"a.png", "b.html", "img/c.jpg"}, []string{}},
+	{[]string{"*.png"}, []string{"b.html", "img/c.jpg"}, []string{"a.png"}},
+	{BinaryPatterns, []string{"b.html"}, []string{"a.png", "img/c.jpg"}},
+	{[]string{"img/*"}, []string{"a.png", "b.html"}, []string{"img/c.jpg"}},
+}
+
+func TestReadPassthrough(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+
+	reads := []string{}
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		reads = append(reads, TransformToLocalPath(path, "dir"))
+		return []byte("hi\n" + path + "\nbye\n"), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		for _, path := range []string{"dir/a.png", "dir/b.html", "dir/img/c.jpg"} {
+			_ = visitor(path, MockFileInfo{}, nil)
+		}
+
+		return nil
+	}
+
+	for i, test := range passthroughTests {
+		reads = []string{}
+
+		site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+			Source:      "dir",
+			Passthrough: test.patterns,
+		}
+
+		if err := site.Read(); err != nil {
+			t.Error("Read errored unexpectedly:", err)
+		}
+
+		sort.Strings(reads)
+
+		if !reflect.DeepEqual(reads, test.expectedReads) {
+			t.Error(
+				"Expected to read", test.expectedReads,
+				"in case", i,
+				"instead read", reads,
+			)
+		}
+
+		passthrough := []string{}
+		for filePath, file := range site.FileMap {
+			if file.Passthrough {
+				passthrough = append(passthrough, filePath)
+
+				if file.Content != nil || file.SourcePath != "dir/"+filePath {
+					t.Error(
+						"Expected passthrough file", filePath,
+						"to have no content and a source path",
+						"in case", i,
+						"instead got", file.Content, file.SourcePath,
+					)
+				}
+			}
+		}
+		sort.Strings(passthrough)
+
+		if !reflect.DeepEqual(passthrough, test.passthrough) {
+			t.Error(
+				"Expected passthrough files", test.passthrough,
+				"in case", i,
+				"instead got", passthrough,
+			)
+		}
+	}
+}
+
 type writeFileStruct struct {
 	path        string
 	destination string
@@ -301,14 +408,22 @@ var writeFileTests = []writeFileStruct{
 		GoSnapFile{Content: []byte("howdy")},
 		writeResultStruct{path: "/c/c/c/c/c/c/c/b.go", content: []byte("howdy"), perm: 0644},
 	},
+	{
+		"renamed/big.png",
+		"/out",
+		GoSnapFile{FileInfo: MockFileInfo{}, SourcePath: "/in/big.png", Passthrough: true},
+		writeResultStruct{path: "/out/renamed/big.png", content: []byte("copied from /in/big.png"), perm: 0777},
+	},
 }
 
 func TestWriteFile(t *testing.T) {
 	oldIoUtilWriteFile := ioUtilWriteFile
 	oldMkdirAll := mkdirAll
+	oldCopyFile := copyFile
 
 	defer func() { ioUtilWriteFile = oldIoUtilWriteFile }()
 	defer func() { mkdirAll = oldMkdirAll }()
+	defer func() { copyFile = oldCopyFile }()
 
 	results := make([]writeResultStruct, len(writeFileTests))
 
@@ -317,6 +432,10 @@ func TestWriteFile(t *testing.T) {
 		results[index] = writeResultStruct{path: path, content: content, perm: perm}
 		return nil
 	}
+	copyFile = func(source string, destination string, perm os.FileMode) error {
+		results[index] = writeResultStruct{path: destination, content: []byte("copied from " + source), perm: perm}
+		return nil
+	}
 	mkdirAll = func(path string, perm os.FileMode) error {
 		return nil
 	}
@@ -369,6 +488,7 @@ var writeTests = []writeStruct{
 		[]string{
 			"/out/file.file",
 			"/out/a/d/e/e/p/l/y/n/e/s/t/e/d/file.go",
+			"/out.gosnap.json",
 		},
 		nil,
 	},
@@ -378,6 +498,7 @@ var writeTests = []writeStruct{
 		},
 		[]string{
 			"/out/file.file",
+			"/out.gosnap.json",
 		},
 		nil,
 	},
@@ -540,6 +661,25 @@ func TestRun(t *testing.T) {
 	for i, test := range runTests {
 		Run(test.fileMap, test.plugins)
 
+		// files are given a FileInfo by the first plugin, check it and leave it out of the comparison
+		for filePath, file := range test.fileMap {
+			if len(test.plugins) == 0 {
+				continue
+			}
+
+			if file.FileInfo == nil || file.FileInfo.Name() != filepath.Base(filePath) || file.FileInfo.Size() != int64(len(file.Content)) {
+				t.Error("Expected", filePath, "to have a FileInfo matching it, instead got", file.FileInfo, "in case", i)
+			}
+
+			if file.Headers().Get("Content-Length") != strconv.Itoa(len(file.Content)) {
+				t.Error("Expected", filePath, "to have a Content-Length matching it, instead got", file.Headers(), "in case", i)
+			}
+
+			file.FileInfo = nil
+			file.header = nil
+			file.synced = contentState{}
+		}
+
 		if !reflect.DeepEqual(test.fileMap, test.expected) {
 			t.Error(
 				"Expected", test.expected,
@@ -646,6 +786,1423 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+type ignoreStruct struct {
+	patterns    []string
+	ignoreFile  string
+	ignoreMap   []string
+	expressions []string
+	expected    []string
+}
+
+var ignorePaths = []string{
+	"dir/index.html",
+	"dir/index.html.swp",
+	"dir/_drafts/a.md",
+	"dir/_drafts/keep.md",
+	"dir/node_modules/lib/lib.js",
+	"dir/nest/node_modules/lib.js",
+	"dir/nest/b.html",
+	"dir/build.log",
+}
+
+var ignoreTests = []ignoreStruct{
+	{
+		nil, "", nil, nil,
+		[]string{"_drafts/a.md", "_drafts/keep.md", "build.log", "index.html", "index.html.swp", "nest/b.html", "nest/node_modules/lib.js", "node_modules/lib/lib.js"},
+	},
+	{
+		[]string{"*.swp", "node_modules/", "# a comment", ""}, "", nil, nil,
+		[]string{"_drafts/a.md", "_drafts/keep.md", "build.log", "index.html", "nest/b.html"},
+	},
+	{
+		[]string{"_drafts/**", "!_drafts/keep.md"}, "", nil, nil,
+		[]string{"_drafts/keep.md", "build.log", "index.html", "index.html.swp", "nest/b.html", "nest/node_modules/lib.js", "node_modules/lib/lib.js"},
+	},
+	{
+		[]string{"/node_modules", "nest/*.html"}, "", nil, nil,
+		[]string{"_drafts/a.md", "_drafts/keep.md", "build.log", "index.html", "index.html.swp", "nest/node_modules/lib.js"},
+	},
+	{
+		nil, "*.log\n**/node_modules\n_drafts/\n", nil, nil,
+		[]string{"index.html", "index.html.swp", "nest/b.html"},
+	},
+	{
+		[]string{"_drafts/"}, "!_drafts/keep.md\n", nil, nil,
+		[]string{"build.log", "index.html", "index.html.swp", "nest/b.html", "nest/node_modules/lib.js", "node_modules/lib/lib.js"},
+	},
+	{
+		nil, "", []string{"dir/build.log", "dir/node_modules"}, []string{`\.swp$`, `^_drafts/a`},
+		[]string{"_drafts/keep.md", "index.html", "nest/b.html", "nest/node_modules/lib.js"},
+	},
+}
+
 func TestIgnore(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+	oldOsStat := osStat
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+	defer func() { osStat = oldOsStat }()
+
+	var ignoreFile string
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		if path == "dir/"+IGNORE_FILE {
+			return []byte(ignoreFile), nil
+		}
+		return []byte(path), nil
+	}
+	osStat = func(path string) (os.FileInfo, error) {
+		if path == "dir/"+IGNORE_FILE && ignoreFile != "" {
+			return MockFileInfo{}, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	// walks like filepath.Walk does, in lexical order and leaving out directories when told to skip them
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		paths := []string{dir}
+		isFile := make(map[string]bool)
+		for _, path := range ignorePaths {
+			isFile[path] = true
+			for parent := filepath.Dir(path); parent != dir; parent = filepath.Dir(parent) {
+				if _, seen := isFile[parent]; !seen {
+					isFile[parent] = false
+					paths = append(paths, parent)
+				}
+			}
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		skipped := ""
+		for _, path := range paths {
+			if skipped != "" && strings.HasPrefix(path, skipped+"/") {
+				continue
+			}
+
+			if visitor(path, SizedFileInfo{isDir: !isFile[path]}, nil) == filepath.SkipDir {
+				skipped = path
+			}
+		}
+
+		return nil
+	}
+
+	for i, test := range ignoreTests {
+		ignoreFile = test.ignoreFile
+
+		site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate), Source: "dir"}
+		site.IgnorePattern(test.patterns...)
+		site.IgnoreAll(test.ignoreMap...)
+		for _, expression := range test.expressions {
+			site.IgnoreExpressions = append(site.IgnoreExpressions, regexp.MustCompile(expression))
+		}
+
+		if err := site.Read(); err != nil {
+			t.Error("Read errored unexpectedly:", err, "in case", i)
+		}
+
+		sitePaths := mapKeys(site.FileMap)
+		sort.Strings(sitePaths)
+
+		if !reflect.DeepEqual(sitePaths, test.expected) {
+			t.Error("Expected", test.expected, "instead got", sitePaths, "in case", i)
+		}
+	}
+
+	if _, err := parseIgnoreRule("[z-a]"); err == nil {
+		t.Error("Expected an impossible character class to error")
+	}
+}
+
+// FileInfo with a fixed modification time so that unchanged files look unchanged between walks
+type WatchFileInfo struct {
+	MockFileInfo
+	modTime time.Time
+}
+
+func (wfi WatchFileInfo) ModTime() time.Time {
+	return wfi.modTime
+}
+
+type watchStruct struct {
+	// path to modification time in seconds for each check of the source
+	directoryStates []map[string]int64
+	expectedReads   []int
+	expected        []string
+}
+
+var watchTests = []watchStruct{
+	{
+		[]map[string]int64{{"a.html": 1, "b.html": 1}, {"a.html": 1, "b.html": 1}},
+		[]int{2, 0},
+		[]string{"a.html", "b.html"},
+	},
+	{
+		[]map[string]int64{{"a.html": 1, "b.html": 1}, {"a.html": 2, "b.html": 1}, {"a.html": 2, "b.html": 1, "c.html": 1}},
+		[]int{2, 1, 1},
+		[]string{"a.html", "b.html", "c.html"},
+	},
+	{
+		[]map[string]int64{{"a.html": 1, "b.html": 1}, {"b.html": 1}},
+		[]int{2, 0},
+		[]string{"b.html"},
+	},
+}
+
+func TestWatch(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+	oldIoUtilWriteFile := ioUtilWriteFile
+	oldMkdirAll := mkdirAll
+	oldNewWatchTicker := newWatchTicker
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+	defer func() { ioUtilWriteFile = oldIoUtilWriteFile }()
+	defer func() { mkdirAll = oldMkdirAll }()
+	defer func() { newWatchTicker = oldNewWatchTicker }()
+
+	var directoryState map[string]int64
+	var checked chan struct{}
+	reads := 0
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		reads++
+		return []byte("hi\n" + path + "\nbye\n"), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		for path, modTime := range directoryState {
+			_ = visitor(path, WatchFileInfo{modTime: time.Unix(modTime, 0)}, nil)
+		}
+
+		// every check of the source walks it once, use that to know when a check is done
+		checked <- struct{}{}
+
+		return nil
+	}
+	ioUtilWriteFile = func(path string, content []byte, perm os.FileMode) error {
+		return nil
+	}
+	mkdirAll = func(path string, perm os.FileMode) error {
+		return nil
+	}
+
+	for i, test := range watchTests {
+		ticks := make(chan time.Time)
+		newWatchTicker = func(interval time.Duration) (<-chan time.Time, func()) {
+			return ticks, func() {}
+		}
+
+		checked = make(chan struct{})
+		directoryState = test.directoryStates[0]
+		reads = 0
+
+		site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+			Source:      "dir",
+			Destination: "/out",
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- site.Watch(ctx) }()
+
+		for step, state := range test.directoryStates {
+			if step > 0 {
+				directoryState = state
+				reads = 0
+				ticks <- time.Now()
+			}
+			<-checked
+
+			if reads != test.expectedReads[step] {
+				t.Error(
+					"Expected", test.expectedReads[step], "reads",
+					"in case", i, "step", step,
+					"instead got", reads,
+				)
+			}
+		}
+
+		cancel()
+		<-done
+
+		sitePaths := mapKeys(site.FileMap)
+		sort.Strings(sitePaths)
+
+		if !reflect.DeepEqual(sitePaths, test.expected) {
+			t.Error(
+				"Expected watch to end with", test.expected,
+				"in case", i,
+				"instead got", sitePaths,
+			)
+		}
+	}
+}
+
+type serveStruct struct {
+	path            string
+	expectedStatus  int
+	expectedBody    string
+	expectedHeaders map[string]string
+}
+
+var serveFileMap = FileMapType{
+	"index.html":      NewFile("index.html", []byte("home")),
+	"style.css":       NewFile("style.css", []byte("a{}")),
+	"blog/index.html": NewFile("blog/index.html", []byte("blog")),
+	"no-headers.txt":  &GoSnapFile{Content: []byte("plain")},
+}
+
+var serveTests = []serveStruct{
+	{"/", 200, "home", map[string]string{"Content-Type": "text/html; charset=utf-8", "Content-Length": "4", "Content-MD5": "EGpsJBuHl/UuHncxe5aiAQ=="}},
+	{"/style.css", 200, "a{}", map[string]string{"Content-Type": "text/css; charset=utf-8", "Content-Length": "3"}},
+	{"/blog", 200, "blog", map[string]string{"Content-Type": "text/html; charset=utf-8"}},
+	{"/blog/", 200, "blog", map[string]string{"Content-Type": "text/html; charset=utf-8"}},
+	{"/no-headers.txt", 200, "plain", map[string]string{"Content-Length": "5"}},
+	{"/style.css/", 404, "404 page not found\n", nil},
+	{"/missing.html", 404, "404 page not found\n", nil},
+}
+
+func TestHandler(t *testing.T) {
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+		FileMap: serveFileMap,
+	}
 
+	handler := site.Handler()
+
+	for i, test := range serveTests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", test.path, nil))
+
+		if recorder.Code != test.expectedStatus {
+			t.Error(
+				"Expected status", test.expectedStatus,
+				"in case", i,
+				"instead got", recorder.Code,
+			)
+		}
+		if recorder.Body.String() != test.expectedBody {
+			t.Error(
+				"Expected body", test.expectedBody,
+				"in case", i,
+				"instead got", recorder.Body.String(),
+			)
+		}
+		for key, value := range test.expectedHeaders {
+			if recorder.Header().Get(key) != value {
+				t.Error(
+					"Expected header", key, "to be", value,
+					"in case", i,
+					"instead got", recorder.Header().Get(key),
+				)
+			}
+		}
+	}
+}
+
+func TestHandlerConcurrent(t *testing.T) {
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+		FileMap: FileMapType{"index.html": NewFile("index.html", []byte("home"))},
+	}
+
+	handler := site.Handler()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+			if recorder.Body.String() != "home" {
+				t.Error("Expected body home, instead got", recorder.Body.String())
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func upper(filePath string, file *GoSnapFile) error {
+	file.Content = bytes.ToUpper(file.Content)
+	return nil
+}
+
+func suffix(filePath string, file *GoSnapFile) error {
+	file.Content = append(file.Content, []byte("!")...)
+	return nil
+}
+
+func failing(filePath string, file *GoSnapFile) error {
+	if filePath == "bad.file" {
+		return errors.New("bad file")
+	}
+	return nil
+}
+
+type eachStruct struct {
+	workers       int
+	plugins       []FilePlugin
+	fileMap       FileMapType
+	expected      FileMapType
+	expectedError bool
+}
+
+var eachTests = []eachStruct{
+	{4, nil, FileMapType{}, FileMapType{}, false},
+	{4, []FilePlugin{upper}, FileMapType{
+		"a.file": &GoSnapFile{Content: []byte("a")},
+		"b.file": &GoSnapFile{Content: []byte("b")},
+	}, FileMapType{
+		"a.file": &GoSnapFile{Content: []byte("A")},
+		"b.file": &GoSnapFile{Content: []byte("B")},
+	}, false},
+	{0, []FilePlugin{suffix, upper}, FileMapType{
+		"a.file": &GoSnapFile{Content: []byte("a")},
+	}, FileMapType{
+		"a.file": &GoSnapFile{Content: []byte("A!")},
+	}, false},
+	{2, []FilePlugin{failing, upper}, FileMapType{
+		"a.file":   &GoSnapFile{Content: []byte("a")},
+		"bad.file": &GoSnapFile{Content: []byte("bad")},
+	}, nil, true},
+}
+
+func TestEachN(t *testing.T) {
+	for i, test := range eachTests {
+		err := EachN(test.workers, test.plugins...)(test.fileMap)
+
+		if (err != nil) != test.expectedError {
+			t.Error(
+				"Expected error", test.expectedError,
+				"in case", i,
+				"instead got", err,
+			)
+		}
+
+		if test.expected != nil && !testEqualFileMap(test.fileMap, test.expected) {
+			t.Error(
+				"Expected", test.expected,
+				"Instead got", test.fileMap,
+				"in case", i,
+			)
+		}
+	}
+}
+
+// FileInfo with a size so that files on a fake disk can be compared
+type SizedFileInfo struct {
+	MockFileInfo
+	size  int64
+	isDir bool
+}
+
+func (sfi SizedFileInfo) Size() int64 {
+	return sfi.size
+}
+func (sfi SizedFileInfo) IsDir() bool {
+	return sfi.isDir
+}
+
+type incrementalWriteStruct struct {
+	disk map[string]string
+	// what the previous build wrote as recorded by it, no record if nil
+	record          map[string]string
+	fileMap         FileMapType
+	prune           bool
+	expectedWrites  []string
+	expectedRemoves []string
+	// defaults to /out
+	destination string
+	// Record of the site, RECORD in the expected writes stands for where it ends up
+	recordFile string
+}
+
+// the record a build which wrote contents leaves behind
+func testRecord(contents map[string]string) string {
+	written := make(map[string]recordedFile, len(contents))
+
+	for filePath, content := range contents {
+		sum := md5.Sum([]byte(content))
+		written[filePath] = recordedFile{MD5: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+	}
+
+	content, _ := json.MarshalIndent(written, "", "  ")
+
+	return string(content) + "\n"
+}
+
+var incrementalWriteTests = []incrementalWriteStruct{
+	{
+		map[string]string{},
+		nil,
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		false,
+		[]string{"/out/a.html", "RECORD"},
+		[]string{},
+		"",
+		"",
+	},
+	{
+		map[string]string{"/out/a.html": "a", "/out/b.html": "old b", "/out/c.html": "cc"},
+		map[string]string{"a.html": "a", "b.html": "old b", "c.html": "cc"},
+		FileMapType{
+			"a.html": &GoSnapFile{Content: []byte("a")},
+			"b.html": &GoSnapFile{Content: []byte("new b")},
+			"c.html": &GoSnapFile{Content: []byte("ccc")},
+		},
+		false,
+		[]string{"/out/b.html", "/out/c.html", "RECORD"},
+		[]string{},
+		"",
+		"",
+	},
+	// without a record nothing on disk is known to be what gosnap wrote
+	{
+		map[string]string{"/out/a.html": "a"},
+		nil,
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		false,
+		[]string{"/out/a.html", "RECORD"},
+		[]string{},
+		"",
+		"",
+	},
+	// files changed on disk since are written again
+	{
+		map[string]string{"/out/a.html": "changed"},
+		map[string]string{"a.html": "a"},
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		false,
+		[]string{"/out/a.html"},
+		[]string{},
+		"",
+		"",
+	},
+	{
+		map[string]string{"/out/a.html": "a", "/out/stale.html": "gone", "/out/old/stale.html": "gone"},
+		map[string]string{"a.html": "a"},
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		false,
+		[]string{},
+		[]string{},
+		"",
+		"",
+	},
+	{
+		map[string]string{
+			"/out/a.html":         "a",
+			"/out/stale.html":     "gone",
+			"/out/old/stale.html": "gone",
+			"/out/CNAME":          "example.com",
+		},
+		map[string]string{"a.html": "a", "old/stale.html": "gone", "stale.html": "gone"},
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		true,
+		[]string{"RECORD"},
+		[]string{"/out/old", "/out/old/stale.html", "/out/stale.html"},
+		"",
+		"",
+	},
+	{
+		map[string]string{"/out/a.html": "a", "/out/stale.html": "not written by gosnap"},
+		map[string]string{"a.html": "a"},
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		true,
+		[]string{},
+		[]string{},
+		"",
+		"",
+	},
+	{
+		map[string]string{"out/a.html": "a", "out/b.html": "b"},
+		map[string]string{"a.html": "a", "b.html": "b"},
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}, "b.html": &GoSnapFile{Content: []byte("b")}},
+		true,
+		[]string{},
+		[]string{},
+		"./out",
+		"",
+	},
+	{
+		map[string]string{"out/a.html": "a", "out/b.html": "b"},
+		map[string]string{"a.html": "a", "b.html": "b"},
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		true,
+		[]string{"RECORD"},
+		[]string{"out/b.html"},
+		"./out",
+		"",
+	},
+	{
+		map[string]string{},
+		nil,
+		FileMapType{"a.html": &GoSnapFile{Content: []byte("a")}},
+		false,
+		[]string{"/out/a.html", "/state/site.json"},
+		[]string{},
+		"",
+		"/state/site.json",
+	},
+}
+
+func TestWriteIncremental(t *testing.T) {
+	oldIoUtilWriteFile := ioUtilWriteFile
+	oldIoUtilReadFile := ioUtilReadFile
+	oldMkdirAll := mkdirAll
+	oldOsStat := osStat
+	oldOsRemove := osRemove
+
+	defer func() { ioUtilWriteFile = oldIoUtilWriteFile }()
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { mkdirAll = oldMkdirAll }()
+	defer func() { osStat = oldOsStat }()
+	defer func() { osRemove = oldOsRemove }()
+
+	var disk map[string]string
+	writes := []string{}
+	removes := []string{}
+
+	ioUtilWriteFile = func(path string, content []byte, perm os.FileMode) error {
+		writes = append(writes, path)
+		return nil
+	}
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return []byte(disk[path]), nil
+	}
+	mkdirAll = func(path string, perm os.FileMode) error {
+		return nil
+	}
+	osStat = func(path string) (os.FileInfo, error) {
+		content, exists := disk[path]
+		if !exists {
+			return nil, os.ErrNotExist
+		}
+		return SizedFileInfo{size: int64(len(content))}, nil
+	}
+	osRemove = func(path string) error {
+		removes = append(removes, path)
+		return nil
+	}
+
+	for i, test := range incrementalWriteTests {
+		disk = test.disk
+		writes = []string{}
+		removes = []string{}
+
+		destination := test.destination
+		if destination == "" {
+			destination = "/out"
+		}
+
+		site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+			FileMap:     test.fileMap,
+			Destination: destination,
+			Prune:       test.prune,
+			Record:      test.recordFile,
+		}
+
+		if test.record != nil {
+			disk[site.recordPath()] = testRecord(test.record)
+		}
+
+		if err := site.Write(); err != nil {
+			t.Error("Write errored unexpectedly:", err)
+		}
+
+		expectedWrites := []string{}
+		for _, write := range test.expectedWrites {
+			if write == "RECORD" {
+				write = site.recordPath()
+			}
+			expectedWrites = append(expectedWrites, write)
+		}
+
+		sort.Strings(writes)
+		sort.Strings(expectedWrites)
+		sort.Strings(removes)
+
+		if !reflect.DeepEqual(writes, expectedWrites) {
+			t.Error(
+				"Expected to write", expectedWrites,
+				"in case", i,
+				"instead wrote", writes,
+			)
+		}
+		if !reflect.DeepEqual(removes, test.expectedRemoves) {
+			t.Error(
+				"Expected to remove", test.expectedRemoves,
+				"in case", i,
+				"instead removed", removes,
+			)
+		}
+	}
+}
+
+func addFile(fm FileMapType) error {
+	fm["new.file"] = &GoSnapFile{Content: []byte("new")}
+	return nil
+}
+
+type modifiedByStruct struct {
+	plugins  []Plugin
+	expected map[string][]string
+}
+
+// every file plugin made by it has the same function name
+func appendText(text string) FilePlugin {
+	return func(filePath string, file *GoSnapFile) error {
+		file.Content = append(file.Content, text...)
+		return nil
+	}
+}
+
+func upperInPlace(fm FileMapType) error {
+	for _, file := range fm {
+		copy(file.Content, bytes.ToUpper(file.Content))
+	}
+	return nil
+}
+
+var modifiedByTests = []modifiedByStruct{
+	{[]Plugin{a}, map[string][]string{"a.file": nil, "b.file": nil}},
+	{[]Plugin{upperInPlace}, map[string][]string{
+		"a.file": {"github.com/caeost/gosnap.upperInPlace"},
+		"b.file": {"github.com/caeost/gosnap.upperInPlace"},
+	}},
+	{[]Plugin{Each(NamedFile("first", appendText("1")), NamedFile("second", appendText("2")))}, map[string][]string{
+		"a.file": {"first", "second"},
+		"b.file": {"first", "second"},
+	}},
+	{[]Plugin{Named("shout", upperInPlace), Named("add", addFile)}, map[string][]string{
+		"a.file":   {"shout"},
+		"b.file":   {"shout"},
+		"new.file": {"add"},
+	}},
+	{[]Plugin{Each(upper), a}, map[string][]string{
+		"a.file": {"github.com/caeost/gosnap.upper"},
+		"b.file": {"github.com/caeost/gosnap.upper"},
+	}},
+	{[]Plugin{Each(suffix), addFile}, map[string][]string{
+		"a.file":   {"github.com/caeost/gosnap.suffix"},
+		"b.file":   {"github.com/caeost/gosnap.suffix"},
+		"new.file": {"github.com/caeost/gosnap.addFile"},
+	}},
+}
+
+func TestNamedErrors(t *testing.T) {
+	broken := func(fm FileMapType) error {
+		return errors.New("broken")
+	}
+
+	err := Run(FileMapType{}, []Plugin{Named("outer", Named("inner", broken))})
+
+	if err == nil || err.Error() != "Error in plugin inner: broken" {
+		t.Error("Expected the error to name the plugin once, instead got", err)
+	}
+
+	err = Run(FileMapType{"bad.file": &GoSnapFile{}}, []Plugin{Each(NamedFile("checker", failing))})
+
+	if err == nil || !strings.HasPrefix(err.Error(), "Error in file plugin checker for bad.file") {
+		t.Error("Expected the error to name the file plugin, instead got", err)
+	}
+}
+
+func TestRunModifiedBy(t *testing.T) {
+	for i, test := range modifiedByTests {
+		fileMap := FileMapType{
+			"a.file": &GoSnapFile{Content: []byte("a")},
+			"b.file": &GoSnapFile{Content: []byte("b")},
+		}
+
+		if err := Run(fileMap, test.plugins); err != nil {
+			t.Error("Run errored unexpectedly:", err)
+		}
+
+		for filePath, file := range fileMap {
+			if !reflect.DeepEqual(file.ModifiedBy, test.expected[filePath]) {
+				t.Error(
+					"Expected", filePath, "to be modified by", test.expected[filePath],
+					"in case", i,
+					"instead got", file.ModifiedBy,
+				)
+			}
+		}
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	fileMap := FileMapType{
+		"b.css": &GoSnapFile{Content: []byte("home"), FileInfo: MockFileInfo{}, SourcePath: "/in/b.css", ModifiedBy: []string{"minify"}},
+		"a.html": &GoSnapFile{
+			Content: []byte(""),
+			header:  http.Header{"Content-Type": []string{"text/plain"}},
+		},
+	}
+
+	expected := []ManifestEntry{
+		{Path: "a.html", Size: 0, MD5: "d41d8cd98f00b204e9800998ecf8427e", ContentType: "text/plain", Mode: "0644"},
+		{Path: "b.css", Size: 4, MD5: "106a6c241b8797f52e1e77317b96a201", ContentType: "text/css; charset=utf-8", SourcePath: "/in/b.css", Mode: "0777", ModifiedBy: []string{"minify"}},
+	}
+
+	entries, err := BuildManifest(fileMap)
+
+	if err != nil {
+		t.Error("BuildManifest errored unexpectedly:", err)
+	}
+
+	if !reflect.DeepEqual(entries, expected) {
+		t.Error(
+			"Expected manifest", expected,
+			"instead got", entries,
+		)
+	}
+}
+
+func TestRegisterFrontmatter(t *testing.T) {
+	oldFrontmatterParsers := frontmatterParsers
+	defer func() { frontmatterParsers = oldFrontmatterParsers }()
+
+	RegisterFrontmatter(DelimitedFrontmatter("JSON", ";;;", json.Unmarshal))
+
+	content, values, err := parseFrontmatter("custom.html", []byte(";;;\n{\"key\": \"value\"}\n;;;\nblah"))
+
+	if err != nil {
+		t.Error("parseFrontmatter errored unexpectedly:", err)
+	}
+	if string(content) != "blah" {
+		t.Error("Expected content blah instead got", string(content))
+	}
+	if !reflect.DeepEqual(values, FrontmatterValueType{"key": "value"}) {
+		t.Error("Expected frontmatter", FrontmatterValueType{"key": "value"}, "instead got", values)
+	}
+}
+
+var getterData = FrontmatterValueType{
+	"title":    "hello",
+	"count":    3,
+	"big":      int64(4),
+	"float":    float64(5),
+	"fraction": 5.5,
+	"draft":    true,
+	"date":     "2017-06-01",
+	"parsed":   time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+	"tags":     []interface{}{"a", "b"},
+	"tag":      "a",
+	"mixed":    []interface{}{"a", 1},
+	"inner":    FrontmatterValueType{"key": "value"},
+}
+
+type getterStruct struct {
+	get           func() (interface{}, error)
+	expected      interface{}
+	expectedError bool
+}
+
+var getterTests = []getterStruct{
+	{func() (interface{}, error) { return getterData.String("title") }, "hello", false},
+	{func() (interface{}, error) { return getterData.String("count") }, "", true},
+	{func() (interface{}, error) { return getterData.String("missing") }, "", true},
+	{func() (interface{}, error) { return getterData.Int("count") }, 3, false},
+	{func() (interface{}, error) { return getterData.Int("big") }, 4, false},
+	{func() (interface{}, error) { return getterData.Int("float") }, 5, false},
+	{func() (interface{}, error) { return getterData.Int("fraction") }, 0, true},
+	{func() (interface{}, error) { return getterData.Bool("draft") }, true, false},
+	{func() (interface{}, error) { return getterData.Bool("title") }, false, true},
+	{func() (interface{}, error) { return getterData.Time("date") }, time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC), false},
+	{func() (interface{}, error) { return getterData.Time("parsed") }, time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC), false},
+	{func() (interface{}, error) { return getterData.Time("title") }, time.Time{}, true},
+	{func() (interface{}, error) { return getterData.StringSlice("tags") }, []string{"a", "b"}, false},
+	{func() (interface{}, error) { return getterData.StringSlice("tag") }, []string{"a"}, false},
+	{func() (interface{}, error) { return getterData.StringSlice("mixed") }, []string(nil), true},
+	{func() (interface{}, error) { return getterData.Map("inner") }, FrontmatterValueType{"key": "value"}, false},
+	{func() (interface{}, error) { return getterData.Map("title") }, FrontmatterValueType(nil), true},
+}
+
+func TestFrontmatterGetters(t *testing.T) {
+	for i, test := range getterTests {
+		value, err := test.get()
+
+		if (err != nil) != test.expectedError {
+			t.Error(
+				"Expected error", test.expectedError,
+				"in case", i,
+				"instead got", err,
+			)
+		}
+		if !reflect.DeepEqual(value, test.expected) {
+			t.Error(
+				"Expected", test.expected,
+				"in case", i,
+				"instead got", value,
+			)
+		}
+	}
+
+	if _, err := getterData.String("missing"); errors.Cause(err) != ErrMissingKey {
+		t.Error("Expected missing key error instead got", err)
+	}
+}
+
+type decodeTarget struct {
+	Title string
+	Count int
+	Tags  []string
+	Inner struct {
+		Key string
+	}
+	Published bool `yaml:"draft"`
+}
+
+func TestFrontmatterDecode(t *testing.T) {
+	data := getterData.Copy()
+	// values added by plugins which can not be decoded are left out
+	data["self"] = &GoSnapFile{Data: data}
+
+	var target decodeTarget
+
+	if err := data.Decode(&target); err != nil {
+		t.Error("Decode errored unexpectedly:", err)
+	}
+
+	if target.Title != "hello" || target.Count != 3 || !reflect.DeepEqual(target.Tags, []string{"a", "b"}) || target.Inner.Key != "value" || !target.Published {
+		t.Error("Decoded frontmatter incorrectly, got", target)
+	}
+}
+
+func TestReadMetadata(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		if path == "dir/site.yaml" {
+			return []byte("title: from file\nnav:\n - home\n"), nil
+		}
+		return []byte("---\ntitle: page\n---\nbody"), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		for _, path := range []string{"dir/site.yaml", "dir/a.html", "dir/b.html"} {
+			_ = visitor(path, MockFileInfo{}, nil)
+		}
+
+		return nil
+	}
+
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+		Source:       "dir",
+		Metadata:     FrontmatterValueType{"title": "from code", "baseURL": "https://example.com"},
+		MetadataFile: "dir/site.yaml",
+	}
+
+	if err := site.Read(); err != nil {
+		t.Error("Read errored unexpectedly:", err)
+	}
+
+	sitePaths := mapKeys(site.FileMap)
+	sort.Strings(sitePaths)
+
+	if !reflect.DeepEqual(sitePaths, []string{"a.html", "b.html"}) {
+		t.Error("Expected metadata file not to be read as a page, instead got", sitePaths)
+	}
+
+	expected := FrontmatterValueType{"title": "from file", "baseURL": "https://example.com", "nav": []interface{}{"home"}}
+
+	for filePath, file := range site.FileMap {
+		if !reflect.DeepEqual(file.Data["site"], expected) || file.Data["title"] != "page" {
+			t.Error(
+				"Expected", filePath, "to have site metadata", expected,
+				"instead got", file.Data,
+			)
+		}
+	}
+
+	if site.Metadata["title"] != "from code" {
+		t.Error("Expected Metadata itself to stay the same, instead got", site.Metadata)
+	}
+}
+
+func TestMetadataForAddedFiles(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+	oldIoUtilWriteFile := ioUtilWriteFile
+	oldMkdirAll := mkdirAll
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+	defer func() { ioUtilWriteFile = oldIoUtilWriteFile }()
+	defer func() { mkdirAll = oldMkdirAll }()
+
+	content := "---\ntitle: page\n---\nbody"
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return []byte(content), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		return visitor("dir/a.html", MockFileInfo{}, nil)
+	}
+	ioUtilWriteFile = func(path string, content []byte, perm os.FileMode) error {
+		return nil
+	}
+	mkdirAll = func(path string, perm os.FileMode) error {
+		return nil
+	}
+
+	var seen interface{}
+
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+		Source:      "dir",
+		Destination: "/out",
+		Metadata:    FrontmatterValueType{"title": "site"},
+		Plugins: []Plugin{
+			func(fileMap FileMapType) error {
+				fileMap["added.html"] = NewFile("added.html", []byte("added"))
+				return nil
+			},
+			func(fileMap FileMapType) error {
+				seen = fileMap["added.html"].Data["site"]
+				return nil
+			},
+		},
+	}
+
+	if err := site.Build(); err != nil {
+		t.Error("Build errored unexpectedly:", err)
+	}
+
+	if !reflect.DeepEqual(seen, FrontmatterValueType{"title": "site"}) {
+		t.Error("Expected a file added by a plugin to have the site metadata for the next plugin, instead got", seen)
+	}
+
+	content = "---\nsite: mine\n---\nbody"
+
+	if err := site.Read(); err == nil {
+		t.Error("Expected a file with site in its frontmatter to fail the read, instead got", site.FileMap["a.html"].Data)
+	}
+}
+
+func TestReadDataDirectory(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+
+	files := map[string]string{
+		"dir/data/team.yaml":       "- name: ann\n- name: bob\n",
+		"dir/data/shop/items.csv":  "name,price\nhat,10\nscarf,12\n",
+		"dir/data/shop/owner.json": `{"name": "cat"}`,
+		"dir/data/README.md":       "about the data",
+		"dir/index.html":           "index",
+	}
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return []byte(files[path]), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		for path := range files {
+			_ = visitor(path, MockFileInfo{}, nil)
+		}
+
+		return nil
+	}
+
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+		Source:        "dir",
+		DataDirectory: "data",
+	}
+
+	if err := site.Read(); err != nil {
+		t.Error("Read errored unexpectedly:", err)
+	}
+
+	sitePaths := mapKeys(site.FileMap)
+	sort.Strings(sitePaths)
+
+	if !reflect.DeepEqual(sitePaths, []string{"data/README.md", "index.html"}) {
+		t.Error("Expected only data files not to be read as pages, instead got", sitePaths)
+	}
+
+	expected := FrontmatterValueType{
+		"team": []interface{}{FrontmatterValueType{"name": "ann"}, FrontmatterValueType{"name": "bob"}},
+		"shop": FrontmatterValueType{
+			"items": []interface{}{
+				FrontmatterValueType{"name": "hat", "price": "10"},
+				FrontmatterValueType{"name": "scarf", "price": "12"},
+			},
+			"owner": FrontmatterValueType{"name": "cat"},
+		},
+	}
+
+	siteData, _ := site.FileMap["index.html"].Data["site"].(FrontmatterValueType)
+
+	if !reflect.DeepEqual(siteData["data"], expected) {
+		t.Error("Expected site data", expected, "instead got", siteData["data"])
+	}
+}
+
+type publishStruct struct {
+	drafts   bool
+	files    map[string]string
+	expected []string
+	errors   bool
+}
+
+var publishTests = []publishStruct{
+	{
+		false,
+		map[string]string{
+			"dir/a.html": "---\ndraft: true\n---\na",
+			"dir/b.html": "---\ndraft: false\n---\nb",
+			"dir/c.html": "---\npublishDate: 2020-01-02\n---\nc",
+			"dir/d.html": "---\npublishDate: 2019-12-31\n---\nd",
+			"dir/e.html": "e",
+		},
+		[]string{"b.html", "d.html", "e.html"},
+		false,
+	},
+	{
+		true,
+		map[string]string{
+			"dir/a.html": "---\ndraft: true\n---\na",
+			"dir/c.html": "---\npublishDate: 2020-01-02\n---\nc",
+		},
+		[]string{"a.html"},
+		false,
+	},
+	{
+		false,
+		map[string]string{"dir/a.html": "---\ndraft: yes please\n---\na"},
+		[]string{},
+		true,
+	},
+}
+
+func TestPublished(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+	oldTimeNow := timeNow
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+	defer func() { timeNow = oldTimeNow }()
+
+	var files map[string]string
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return []byte(files[path]), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		for path := range files {
+			_ = visitor(path, MockFileInfo{}, nil)
+		}
+
+		return nil
+	}
+	timeNow = func() time.Time {
+		return time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	for i, test := range publishTests {
+		files = test.files
+
+		site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate), Source: "dir", Drafts: test.drafts}
+		err := site.Read()
+
+		if test.errors {
+			if err == nil {
+				t.Error("Expected Read to error in case", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("Read errored unexpectedly:", err, "in case", i)
+		}
+
+		sitePaths := mapKeys(site.FileMap)
+		sort.Strings(sitePaths)
+
+		if !reflect.DeepEqual(sitePaths, test.expected) {
+			t.Error("Expected", test.expected, "to be published, instead got", sitePaths, "in case", i)
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+
+	files := map[string]string{
+		"dir/a.html": "---\nheaders:\n  Cache-Control: max-age=60\n  Link: [</a.css>; rel=preload, </b.js>; rel=preload]\n  Content-Type: text/plain\n---\nhome",
+		"dir/b.html": "---\nheaders:\n  Access-Control-Max-Age: 86400\n  Retry-After: [120]\n---\nnumbers",
+		"dir/c.html": "---\nheaders:\n  X-Nested:\n    inner: value\n---\nbad",
+		"dir/d.html": "---\nheaders:\n  X-Lines: |\n    first\n    add_header X-Injected yes;\n---\nbad",
+		"dir/e.html": "---\nheaders:\n  X Spaced: value\n---\nbad",
+		"dir/f.html": "---\nheaders:\n  X-Lines: [ok, \"a\\r\\nb\"]\n---\nbad",
+	}
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return []byte(files[path]), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		_ = visitor("dir/a.html", WatchFileInfo{modTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, nil)
+
+		return nil
+	}
+
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate), Source: "dir"}
+
+	if err := site.Read(); err != nil {
+		t.Error("Read errored unexpectedly:", err)
+	}
+
+	file := site.FileMap["a.html"]
+	expected := http.Header{
+		"Cache-Control":  []string{"max-age=60"},
+		"Link":           []string{"</a.css>; rel=preload", "</b.js>; rel=preload"},
+		"Content-Type":   []string{"text/plain"},
+		"Content-Length": []string{"4"},
+		"Content-Md5":    []string{"EGpsJBuHl/UuHncxe5aiAQ=="},
+		"Last-Modified":  []string{"Wed, 01 Jan 2020 00:00:00 GMT"},
+	}
+
+	if !reflect.DeepEqual(file.Headers(), expected) {
+		t.Error("Expected headers", expected, "instead got", file.Headers())
+	}
+
+	if modified, err := http.ParseTime(file.Headers().Get("Last-Modified")); err != nil || !modified.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Last-Modified to parse back to the modification time, instead got", modified, err)
+	}
+
+	copied := file.Copy()
+	file.Headers().Set("Cache-Control", "no-cache")
+	file.Content = []byte("changed")
+
+	if file.Headers().Get("Content-Length") != "4" {
+		t.Error("Expected headers to only follow the content once the plugin is done, instead got", file.Headers())
+	}
+
+	if err := Run(site.FileMap, []Plugin{a}); err != nil {
+		t.Error("Run errored unexpectedly:", err)
+	}
+
+	if file.Headers().Get("Cache-Control") != "no-cache" || file.Headers().Get("Content-Length") != "7" {
+		t.Error("Expected headers to keep changes and follow the content, instead got", file.Headers())
+	}
+	if copied.Headers().Get("Cache-Control") != "max-age=60" || copied.Headers().Get("Content-Length") != "4" {
+		t.Error("Expected copied headers to stay the same, instead got", copied.Headers())
+	}
+
+	// plugins which change Content in place are noticed as well
+	upper := func(fileMap FileMapType) error {
+		content := fileMap["a.html"].Content
+		copy(content, bytes.ToUpper(content))
+
+		return nil
+	}
+
+	if err := Run(site.FileMap, []Plugin{upper}); err != nil {
+		t.Error("Run errored unexpectedly:", err)
+	}
+
+	sum := md5.Sum([]byte("CHANGED"))
+	if file.Headers().Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Error("Expected Content-MD5 to follow content changed in place, instead got", file.Headers().Get("Content-MD5"))
+	}
+
+	numbers, err := site.ReadFile("dir/b.html")
+
+	if err != nil {
+		t.Error("Expected numeric headers to be read, instead got", err)
+	} else if numbers.Headers().Get("Access-Control-Max-Age") != "86400" || numbers.Headers().Get("Retry-After") != "120" {
+		t.Error("Expected numeric headers as text, instead got", numbers.Headers())
+	}
+
+	if _, err := site.ReadFile("dir/c.html"); err == nil {
+		t.Error("Expected headers which are maps to error")
+	}
+
+	// either would add lines to a response or the configuration ServerHeaders writes
+	for _, filePath := range []string{"dir/d.html", "dir/e.html", "dir/f.html"} {
+		if _, err := site.ReadFile(filePath); err == nil {
+			t.Error("Expected header with a line break or an invalid name to error in", filePath)
+		}
+	}
+}
+
+func shout(fileMap FileMapType) error {
+	file := fileMap["a.txt"]
+	file.Content = bytes.ToUpper(append(file.Content, '!'))
+
+	delete(fileMap, "a.txt")
+	fileMap["b.txt"] = file
+
+	fileMap["c.txt"] = &GoSnapFile{Content: []byte("new"), FileInfo: NewFileInfo("c.txt", 3, 0600, time.Unix(5, 0))}
+	fileMap["d.txt"] = &GoSnapFile{Content: []byte("generated")}
+
+	return nil
+}
+
+func TestFileInfo(t *testing.T) {
+	oldIoUtilReadFile := ioUtilReadFile
+	oldFilepathWalk := filepathWalk
+	oldTimeNow := timeNow
+
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { filepathWalk = oldFilepathWalk }()
+	defer func() { timeNow = oldTimeNow }()
+
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return []byte("hello"), nil
+	}
+	filepathWalk = func(dir string, visitor filepath.WalkFunc) error {
+		_ = visitor("dir/a.txt", WatchFileInfo{modTime: time.Unix(1, 0)}, nil)
+
+		return nil
+	}
+	timeNow = func() time.Time {
+		return time.Unix(10, 0)
+	}
+
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate), Source: "dir"}
+
+	if err := site.Read(); err != nil {
+		t.Error("Read errored unexpectedly:", err)
+	}
+
+	copied := site.FileMap["a.txt"].Copy()
+
+	if err := Run(site.FileMap, []Plugin{shout}); err != nil {
+		t.Error("Run errored unexpectedly:", err)
+	}
+
+	_, _ = copied.Write([]byte(" there"))
+
+	type expectedInfo struct {
+		name    string
+		size    int64
+		mode    os.FileMode
+		modTime time.Time
+	}
+
+	expected := map[*GoSnapFile]expectedInfo{
+		site.FileMap["b.txt"]:            {"b.txt", 6, 0777, time.Unix(1, 0)},
+		site.FileMap["c.txt"]:            {"c.txt", 3, 0600, time.Unix(5, 0)},
+		site.FileMap["d.txt"]:            {"d.txt", 9, DEFAULT_PERM, time.Unix(10, 0)},
+		copied:                           {"a.txt", 11, 0777, time.Unix(1, 0)},
+		NewFile("e/f.txt", []byte("hi")): {"f.txt", 2, DEFAULT_PERM, time.Unix(10, 0)},
+	}
+
+	for file, info := range expected {
+		actual := expectedInfo{file.FileInfo.Name(), file.FileInfo.Size(), file.FileInfo.Mode(), file.FileInfo.ModTime()}
+
+		if !reflect.DeepEqual(actual, info) {
+			t.Error("Expected file info", info, "instead got", actual)
+		}
+	}
+
+	timeNow = func() time.Time {
+		return time.Unix(20, 0)
+	}
+
+	copies := FileMapType{"b.txt": site.FileMap["b.txt"].Copy()}
+	_ = Run(copies, []Plugin{a})
+
+	if copies["b.txt"].FileInfo.ModTime() != time.Unix(1, 0) {
+		t.Error("Expected copying a file not to change its modification time, instead got", copies["b.txt"].FileInfo.ModTime())
+	}
+}
+
+func TestWriteModTime(t *testing.T) {
+	oldIoUtilWriteFile := ioUtilWriteFile
+	oldIoUtilReadFile := ioUtilReadFile
+	oldMkdirAll := mkdirAll
+	oldOsStat := osStat
+	oldTimeNow := timeNow
+
+	defer func() { ioUtilWriteFile = oldIoUtilWriteFile }()
+	defer func() { ioUtilReadFile = oldIoUtilReadFile }()
+	defer func() { mkdirAll = oldMkdirAll }()
+	defer func() { osStat = oldOsStat }()
+	defer func() { timeNow = oldTimeNow }()
+
+	sum := func(content string) string {
+		sum := md5.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	record, _ := json.Marshal(map[string]recordedFile{
+		"a.html": {MD5: sum("a"), Size: 1, ModTime: time.Unix(5, 0)},
+		"b.html": {MD5: sum("b"), Size: 1, ModTime: time.Unix(5, 0)},
+	})
+	written := map[string][]byte{}
+
+	ioUtilWriteFile = func(path string, content []byte, perm os.FileMode) error {
+		written[path] = content
+		return nil
+	}
+	ioUtilReadFile = func(path string) ([]byte, error) {
+		return record, nil
+	}
+	mkdirAll = func(path string, perm os.FileMode) error {
+		return nil
+	}
+	osStat = func(path string) (os.FileInfo, error) {
+		if path == "/out.gosnap.json" {
+			return SizedFileInfo{}, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	timeNow = func() time.Time {
+		return time.Unix(10, 0)
+	}
+
+	source := func(content string) *GoSnapFile {
+		return &GoSnapFile{Content: []byte(content), FileInfo: NewFileInfo("", 1, DEFAULT_PERM, time.Unix(1, 0))}
+	}
+
+	site := GoSnap{Logger: log.New(ioutil.Discard, "discard", log.Ldate),
+		FileMap:     FileMapType{"a.html": source("a"), "b.html": source("b"), "c.html": source("c")},
+		Destination: "/out",
+	}
+
+	// changed in place after the plugins ran
+	site.FileMap["b.html"].Content[0] = 'B'
+
+	if err := site.Write(); err != nil {
+		t.Error("Write errored unexpectedly:", err)
+	}
+
+	expected := map[string]time.Time{
+		// the same as the previous build
+		"a.html": time.Unix(5, 0),
+		// different from the previous build
+		"b.html": time.Unix(10, 0),
+		// not written before, it keeps the time of its source
+		"c.html": time.Unix(1, 0),
+	}
+
+	for filePath, modTime := range expected {
+		if actual := site.FileMap[filePath].FileInfo.ModTime(); !actual.Equal(modTime) {
+			t.Error("Expected", filePath, "to be modified at", modTime, "instead got", actual)
+		}
+	}
+
+	recorded := map[string]recordedFile{}
+	if err := json.Unmarshal(written["/out.gosnap.json"], &recorded); err != nil {
+		t.Fatal("Expected a record to be written, instead got", err)
+	}
+	if recorded["b.html"].MD5 != sum("B") || !recorded["b.html"].ModTime.Equal(time.Unix(10, 0)) {
+		t.Error("Expected the record to follow the changed file, instead got", recorded["b.html"])
+	}
 }
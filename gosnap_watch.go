@@ -0,0 +1,152 @@
+package gosnap
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// default time between checks of Source for changes
+const (
+	DEFAULT_WATCH_INTERVAL = 500 * time.Millisecond
+)
+
+var newWatchTicker = func(interval time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(interval)
+
+	return ticker.C, ticker.Stop
+}
+
+// state of Source between checks, kept so only changed files have to be read again
+type watchState struct {
+	fileInfos map[string]os.FileInfo
+	files     FileMapType
+	data      map[string]interface{}
+	metadata  os.FileInfo
+	// when the next file held back by its publishDate is due
+	nextPublish time.Time
+}
+
+func unchanged(a os.FileInfo, b os.FileInfo) bool {
+	return a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size() && a.Mode() == b.Mode()
+}
+
+// walks Source and re-reads any file that was added or changed since the last check,
+// returns whether anything changed at all
+func (gs *GoSnap) refresh(state *watchState) (bool, error) {
+	changed := false
+	current := make(map[string]os.FileInfo)
+	var readErr error
+
+	walkErr := gs.walkSource(func(filePath string, fileInfo os.FileInfo) error {
+		current[filePath] = fileInfo
+
+		if previous, exists := state.fileInfos[filePath]; exists && unchanged(previous, fileInfo) {
+			return nil
+		}
+
+		if err := gs.readInto(state.files, state.data, filePath, fileInfo); err != nil {
+			// keep going with the other files and try this one again on the next check
+			if readErr == nil {
+				readErr = err
+			}
+			if previous, exists := state.fileInfos[filePath]; exists {
+				current[filePath] = previous
+			} else {
+				delete(current, filePath)
+			}
+			return nil
+		}
+
+		gs.Printf("read changed file %v", filePath)
+		changed = true
+
+		return nil
+	})
+
+	if walkErr != nil {
+		// an incomplete walk can not tell which files were removed
+		return changed, walkErr
+	}
+
+	for filePath := range state.fileInfos {
+		if _, exists := current[filePath]; !exists {
+			gs.Printf("removed file %v", filePath)
+			delete(state.files, TransformToLocalPath(filePath, gs.Source))
+			delete(state.data, TransformToLocalPath(filePath, gs.Source))
+			changed = true
+		}
+	}
+
+	state.fileInfos = current
+
+	if gs.MetadataFile != "" {
+		metadata, err := osStat(gs.MetadataFile)
+
+		if err == nil && (state.metadata == nil || !unchanged(state.metadata, metadata)) {
+			state.metadata = metadata
+			changed = true
+		}
+	}
+
+	return changed, readErr
+}
+
+// Watch builds the site and then keeps rebuilding it whenever files in Source change until ctx is done.
+// Plugins always receive freshly read files, but only the files that changed are read from disk again.
+func (gs *GoSnap) Watch(ctx context.Context) error {
+	interval := gs.WatchInterval
+	if interval <= 0 {
+		interval = DEFAULT_WATCH_INTERVAL
+	}
+
+	state := &watchState{fileInfos: make(map[string]os.FileInfo), files: make(FileMapType), data: make(map[string]interface{})}
+
+	rebuild := func(force bool) error {
+		changed, err := gs.refresh(state)
+
+		if err != nil {
+			return errors.Wrap(err, "Rebuild failed at read step")
+		}
+
+		// scheduled files have to show up even when nothing else changed
+		due := !state.nextPublish.IsZero() && !timeNow().Before(state.nextPublish)
+
+		if !changed && !force && !due {
+			return nil
+		}
+
+		gs.FileMap = state.files.Copy()
+
+		if err := gs.attachMetadata(gs.FileMap, state.data); err != nil {
+			return errors.Wrap(err, "Rebuild failed at read step")
+		}
+
+		if state.nextPublish, err = gs.removeUnpublished(gs.FileMap, timeNow()); err != nil {
+			return errors.Wrap(err, "Rebuild failed at read step")
+		}
+
+		return gs.process()
+	}
+
+	gs.Print("watching source for changes")
+	if err := rebuild(true); err != nil {
+		gs.Printf("Error running build. %v", err)
+	}
+
+	ticks, stop := newWatchTicker(interval)
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticks:
+			if err := rebuild(false); err != nil {
+				gs.Printf("Error running build. %v", err)
+			}
+		}
+	}
+}
@@ -1,9 +1,16 @@
 package gosnap
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -18,16 +25,36 @@ const (
 
 var mkdirAll = os.MkdirAll
 var ioUtilWriteFile = ioutil.WriteFile
+var osStat = os.Stat
+var osRemove = os.Remove
+
+// streams a file from source to destination without holding it in memory
+var copyFile = func(source string, destination string, perm os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
 
 func (gs *GoSnap) WriteFile(filePath string, file GoSnapFile) error {
 	if gs.Destination == "" {
 		return errors.New("No Destination set in GoSnap object")
 	}
 
-	perm := DEFAULT_PERM
-	if file.FileInfo != nil {
-		perm = file.FileInfo.Mode()
-	}
+	perm := fileMode(&file)
 
 	finalPath := path.Join(gs.Destination, filePath)
 
@@ -37,9 +64,139 @@ func (gs *GoSnap) WriteFile(filePath string, file GoSnapFile) error {
 		return errors.Wrapf(mkdirErr, "Could not create required directories for %v", finalPath)
 	}
 
+	if file.Passthrough {
+		return errors.Wrapf(copyFile(file.SourcePath, finalPath, perm), "Could not copy %v", file.SourcePath)
+	}
+
 	return ioUtilWriteFile(finalPath, file.Content, perm)
 }
 
+// what a build wrote to one path of Destination
+type recordedFile struct {
+	// empty for passthrough files, which are never read
+	MD5     string    `json:"md5,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// what would be recorded for the file, it has to be synced
+func recordOf(file *GoSnapFile) recordedFile {
+	if file.Passthrough {
+		return recordedFile{Size: file.FileInfo.Size(), ModTime: file.FileInfo.ModTime()}
+	}
+
+	return recordedFile{MD5: hex.EncodeToString(file.synced.sum[:]), Size: int64(file.synced.length), ModTime: file.FileInfo.ModTime()}
+}
+
+// whether the file at finalPath already holds exactly what would be written to it, going by what the
+// previous build recorded writing there so that nothing has to be read
+func unchangedOnDisk(finalPath string, file *GoSnapFile, previous recordedFile, recorded bool) bool {
+	existing, err := osStat(finalPath)
+	if err != nil || existing.IsDir() {
+		return false
+	}
+
+	// passthrough files are not read so go by size and whether the copy is newer than the source
+	if file.Passthrough {
+		source, err := osStat(file.SourcePath)
+
+		return err == nil && source.Size() == existing.Size() && !existing.ModTime().Before(source.ModTime())
+	}
+
+	return recorded && existing.Size() == previous.Size && recordOf(file).MD5 == previous.MD5
+}
+
+// added to Destination for the default Record, for example out.gosnap.json next to out
+const RECORD_EXTENSION = ".gosnap.json"
+
+func (gs *GoSnap) recordPath() string {
+	if gs.Record != "" {
+		return gs.Record
+	}
+
+	destination, err := filepath.Abs(gs.Destination)
+	if err != nil {
+		destination = filepath.Clean(gs.Destination)
+	}
+
+	return destination + RECORD_EXTENSION
+}
+
+// what the previous build wrote to each local path and the record as it is on disk, nothing if it left no record
+func (gs *GoSnap) previousRecord() (map[string]recordedFile, []byte, error) {
+	recordPath := gs.recordPath()
+
+	if _, err := osStat(recordPath); os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+
+	content, err := ioUtilReadFile(recordPath)
+
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Could not read %v", recordPath)
+	}
+
+	written := make(map[string]recordedFile)
+
+	if err := json.Unmarshal(content, &written); err != nil {
+		return nil, nil, errors.Wrapf(err, "Could not parse %v", recordPath)
+	}
+
+	return written, content, nil
+}
+
+// records what was written, only rewriting the record when it changed
+func (gs *GoSnap) recordWritten(written map[string]recordedFile, previous []byte) error {
+	// map keys are sorted so the record only changes when what was written did
+	content, err := json.MarshalIndent(written, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Could not encode written files")
+	}
+
+	content = append(content, '\n')
+
+	if bytes.Equal(content, previous) {
+		return nil
+	}
+
+	return ioUtilWriteFile(gs.recordPath(), content, DEFAULT_PERM)
+}
+
+// removes files the previous build wrote which are not in FileMap anymore, along with directories they leave
+// empty. Anything else in Destination, like .git or a CNAME, is left alone.
+func (gs *GoSnap) prune(written map[string]recordedFile) error {
+	destination := filepath.Clean(gs.Destination)
+
+	for internalPath := range written {
+		if _, exists := gs.FileMap[internalPath]; exists {
+			continue
+		}
+
+		filePath := filepath.Join(destination, filepath.FromSlash(internalPath))
+
+		// the record is only trusted with paths inside of Destination
+		if relative, err := filepath.Rel(destination, filePath); err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+			continue
+		}
+
+		gs.Printf("remove stale file %v", filePath)
+
+		if err := osRemove(filePath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Could not remove stale file %v", filePath)
+		}
+
+		// removing a directory which is not empty just fails
+		for directory := filepath.Dir(filePath); directory != destination && directory != "."; directory = filepath.Dir(directory) {
+			if osRemove(directory) != nil {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
 func cleanOutput(destination string) error {
 	fileInfo, err := os.Stat(destination)
 	if err != nil {
@@ -71,7 +228,27 @@ func (gs *GoSnap) Write() error {
 		}
 	}
 
+	previous, previousContent, err := gs.previousRecord()
+
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]recordedFile, len(gs.FileMap))
+	unchanged := 0
+
 	for filePath, file := range gs.FileMap {
+		recorded, exists := previous[filePath]
+		file.sync(filePath)
+		file.settleModTime(recorded, exists)
+		written[filePath] = recordOf(file)
+
+		// skipping identical files keeps their modification times so tools watching the output only see real changes
+		if !gs.Clean && unchangedOnDisk(path.Join(gs.Destination, filePath), file, recorded, exists) {
+			unchanged++
+			continue
+		}
+
 		err := gs.WriteFile(filePath, *file)
 
 		if err != nil {
@@ -79,5 +256,19 @@ func (gs *GoSnap) Write() error {
 		}
 	}
 
+	if unchanged > 0 {
+		gs.Printf("skipped %v unchanged files", unchanged)
+	}
+
+	if gs.Prune && !gs.Clean {
+		if err := gs.prune(previous); err != nil {
+			return errors.Wrapf(err, "Could not remove stale files from %v", gs.Destination)
+		}
+	}
+
+	if err := gs.recordWritten(written, previousContent); err != nil {
+		return errors.Wrapf(err, "Could not record written files in %v", gs.recordPath())
+	}
+
 	return nil
 }
@@ -0,0 +1,177 @@
+package plugins
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/caeost/gosnap"
+)
+
+// Collection groups files by a glob on their path and/or by "collection: name" in their frontmatter
+type Collection struct {
+	Name string
+	// optional glob matched against the path of each file
+	Pattern string
+	// frontmatter key to sort by, for example "date", files without it go last
+	SortBy  string
+	Reverse bool
+}
+
+// One file of a collection as seen by templates
+type CollectionItem struct {
+	Path string
+	Data gosnap.FrontmatterValueType
+	File *gosnap.GoSnapFile
+}
+
+func (collection Collection) contains(filePath string, file *gosnap.GoSnapFile) bool {
+	if collection.Pattern != "" {
+		if matched, _ := path.Match(collection.Pattern, filePath); matched {
+			return true
+		}
+	}
+
+	switch names := file.Data["collection"].(type) {
+	case string:
+		return names == collection.Name
+	case []interface{}:
+		for _, name := range names {
+			if name == collection.Name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+
+	return 0, false
+}
+
+// orders frontmatter values of the same kind, dates written as strings sort correctly when in ISO format
+func lessValue(a interface{}, b interface{}) bool {
+	if aTime, ok := a.(time.Time); ok {
+		if bTime, ok := b.(time.Time); ok {
+			return aTime.Before(bTime)
+		}
+	}
+
+	if aNumber, ok := toFloat(a); ok {
+		if bNumber, ok := toFloat(b); ok {
+			return aNumber < bNumber
+		}
+	}
+
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+func (collection Collection) sort(items []*CollectionItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		// ties and unsorted collections fall back to the path so builds are repeatable
+		if collection.SortBy == "" {
+			return items[i].Path < items[j].Path
+		}
+
+		a, aExists := items[i].Data[collection.SortBy]
+		b, bExists := items[j].Data[collection.SortBy]
+
+		switch {
+		case !aExists || !bExists:
+			return aExists
+		case lessValue(a, b):
+			return !collection.Reverse
+		case lessValue(b, a):
+			return collection.Reverse
+		}
+
+		return items[i].Path < items[j].Path
+	})
+}
+
+// finds the items of the collection in sorted order, filePaths are the paths of fileMap in order
+func (collection Collection) gather(fileMap gosnap.FileMapType, filePaths []string) []*CollectionItem {
+	items := []*CollectionItem{}
+
+	for _, filePath := range filePaths {
+		file := fileMap[filePath]
+
+		if collection.contains(filePath, file) {
+			if file.Data == nil {
+				file.Data = make(gosnap.FrontmatterValueType)
+			}
+
+			items = append(items, &CollectionItem{Path: filePath, Data: file.Data, File: file})
+		}
+	}
+
+	collection.sort(items)
+
+	return items
+}
+
+// paths of the files in fileMap in order, so that items with equal sort values are always ordered the same
+func sortedPaths(fileMap gosnap.FileMapType) []string {
+	filePaths := make([]string, 0, len(fileMap))
+
+	for filePath := range fileMap {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+
+	return filePaths
+}
+
+// Collections gathers the files of every collection and sorts them. Every file gets the sorted lists in
+// "collections" (for example {{range .collections.posts}}) and every file in a collection gets the
+// files before and after it as "previous" and "next", which are nil at either end.
+// Use it after plugins which rename files, like Permalinks, so that the paths of the items are final.
+func Collections(collections ...Collection) gosnap.Plugin {
+	return gosnap.Named("plugins.Collections", func(fileMap gosnap.FileMapType) error {
+		lists := make(map[string][]*CollectionItem, len(collections))
+		filePaths := sortedPaths(fileMap)
+
+		for _, collection := range collections {
+			items := collection.gather(fileMap, filePaths)
+
+			for i, item := range items {
+				var previous, next *CollectionItem
+
+				if i > 0 {
+					previous = items[i-1]
+				}
+				if i < len(items)-1 {
+					next = items[i+1]
+				}
+
+				item.Data["previous"] = previous
+				item.Data["next"] = next
+			}
+
+			lists[collection.Name] = items
+		}
+
+		for _, file := range fileMap {
+			if file.Data == nil {
+				file.Data = make(gosnap.FrontmatterValueType)
+			}
+
+			file.Data["collections"] = lists
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,272 @@
+package plugins
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// FeedConfig configures the RSS and Atom feeds for a collection, the files use "title", "date" and "author"
+// from their frontmatter, and their content from before it was put into a layout if Render left it as "content".
+// Files without a date go by when their source last changed. RSS only takes email addresses as author, other
+// authors are written as dc:creator. Sort the collection newest first, for example with SortBy "date" and Reverse.
+type FeedConfig struct {
+	Collection Collection
+	// url the site is served from, for example "https://example.com"
+	BaseURL     string
+	Title       string
+	Description string
+	// used for files without an author of their own
+	Author string
+	// maximum number of files in the feeds, all of them if 0
+	Limit int
+	// put the content of the files into Atom as content instead of summary
+	FullContent bool
+	// default to rss.xml and atom.xml
+	RSSPath  string
+	AtomPath string
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Author      string `xml:"author,omitempty"`
+	Creator     string `xml:"dc:creator,omitempty"`
+	Description string `xml:"description"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Summary *atomText   `xml:"summary,omitempty"`
+	Content *atomText   `xml:"content,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+var timeNow = time.Now
+
+// what the feeds need to know about one file
+type feedEntry struct {
+	title   string
+	link    string
+	date    time.Time
+	author  string
+	content string
+}
+
+func (feed FeedConfig) entries(fileMap gosnap.FileMapType) []feedEntry {
+	items := feed.Collection.gather(fileMap, sortedPaths(fileMap))
+
+	if feed.Limit > 0 && len(items) > feed.Limit {
+		items = items[:feed.Limit]
+	}
+
+	entries := make([]feedEntry, len(items))
+
+	for i, item := range items {
+		entry := feedEntry{
+			title:   item.Path,
+			link:    absoluteURL(feed.BaseURL, item.Path),
+			author:  feed.Author,
+			content: string(item.File.Content),
+		}
+
+		if content, exists := item.Data["content"]; exists {
+			entry.content = fmt.Sprint(content)
+		}
+		if title, exists := item.Data["title"]; exists {
+			entry.title = fmt.Sprint(title)
+		}
+		if author, exists := item.Data["author"]; exists {
+			entry.author = fmt.Sprint(author)
+		}
+		if date, err := item.Data.Time("date"); err == nil {
+			entry.date = date
+		} else {
+			entry.date = lastModified(item.File)
+		}
+
+		entries[i] = entry
+	}
+
+	return entries
+}
+
+// the feeds were last updated when their newest entry was, or now if none of them have a date
+func newest(entries []feedEntry) time.Time {
+	updated := time.Time{}
+
+	for _, entry := range entries {
+		if entry.date.After(updated) {
+			updated = entry.date
+		}
+	}
+
+	if updated.IsZero() {
+		return timeNow()
+	}
+
+	return updated
+}
+
+func (feed FeedConfig) rss(entries []feedEntry, updated time.Time) rssFeed {
+	channel := rssChannel{
+		Title:         feed.Title,
+		Link:          absoluteURL(feed.BaseURL, ""),
+		Description:   feed.Description,
+		LastBuildDate: updated.Format(time.RFC1123Z),
+	}
+
+	for _, entry := range entries {
+		item := rssItem{Title: entry.title, Link: entry.link, GUID: entry.link, Description: entry.content}
+
+		// RSS only allows an email address as author, names go in dc:creator instead
+		if strings.Contains(entry.author, "@") {
+			item.Author = entry.author
+		} else {
+			item.Creator = entry.author
+		}
+
+		if !entry.date.IsZero() {
+			item.PubDate = entry.date.Format(time.RFC1123Z)
+		}
+
+		channel.Items = append(channel.Items, item)
+	}
+
+	return rssFeed{Version: "2.0", DC: "http://purl.org/dc/elements/1.1/", Channel: channel}
+}
+
+func (feed FeedConfig) atom(entries []feedEntry, updated time.Time, atomPath string) atomFeed {
+	atom := atomFeed{
+		Title:   feed.Title,
+		ID:      absoluteURL(feed.BaseURL, ""),
+		Links:   []atomLink{{Href: absoluteURL(feed.BaseURL, "")}, {Href: absoluteURL(feed.BaseURL, atomPath), Rel: "self"}},
+		Updated: updated.UTC().Format(time.RFC3339),
+	}
+
+	if feed.Author != "" {
+		atom.Author = &atomAuthor{Name: feed.Author}
+	}
+
+	for _, entry := range entries {
+		// Atom requires a date for every entry
+		date := entry.date
+		if date.IsZero() {
+			date = updated
+		}
+
+		atomEntry := atomEntry{
+			Title:   entry.title,
+			ID:      entry.link,
+			Link:    atomLink{Href: entry.link},
+			Updated: date.UTC().Format(time.RFC3339),
+		}
+
+		if entry.author != "" {
+			atomEntry.Author = &atomAuthor{Name: entry.author}
+		}
+
+		if feed.FullContent {
+			atomEntry.Content = &atomText{Type: "html", Body: entry.content}
+		} else {
+			atomEntry.Summary = &atomText{Type: "html", Body: entry.content}
+		}
+
+		atom.Entries = append(atom.Entries, atomEntry)
+	}
+
+	return atom
+}
+
+func marshalXML(value interface{}) ([]byte, error) {
+	content, err := xml.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+// Feed adds RSS 2.0 and Atom feeds of a collection, run it after Render so that the feeds get the rendered content
+func Feed(feed FeedConfig) gosnap.Plugin {
+	rssPath := feed.RSSPath
+	if rssPath == "" {
+		rssPath = "rss.xml"
+	}
+
+	atomPath := feed.AtomPath
+	if atomPath == "" {
+		atomPath = "atom.xml"
+	}
+
+	return gosnap.Named("plugins.Feed", func(fileMap gosnap.FileMapType) error {
+		entries := feed.entries(fileMap)
+		updated := newest(entries)
+
+		rss, err := marshalXML(feed.rss(entries, updated))
+
+		if err != nil {
+			return errors.Wrapf(err, "Could not encode RSS feed %v", rssPath)
+		}
+
+		atom, err := marshalXML(feed.atom(entries, updated, atomPath))
+
+		if err != nil {
+			return errors.Wrapf(err, "Could not encode Atom feed %v", atomPath)
+		}
+
+		fileMap[rssPath] = gosnap.NewFile(rssPath, rss)
+		fileMap[atomPath] = gosnap.NewFile(atomPath, atom)
+
+		return nil
+	})
+}
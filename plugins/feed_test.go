@@ -0,0 +1,122 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caeost/gosnap"
+)
+
+func TestFeedAuthors(t *testing.T) {
+	fileMap := gosnap.FileMapType{
+		"posts/a.html": newPage(gosnap.FrontmatterValueType{"date": "2020-01-02", "author": "jane@example.com (Jane)"}),
+		"posts/b.html": newPage(gosnap.FrontmatterValueType{"date": "2020-01-01"}),
+	}
+
+	feed := FeedConfig{Collection: Collection{Name: "posts", Pattern: "posts/*", SortBy: "date", Reverse: true}, BaseURL: "https://example.com", Author: "Site Team"}
+
+	if err := Feed(feed)(fileMap); err != nil {
+		t.Fatal("Feed errored unexpectedly:", err)
+	}
+
+	rss := string(fileMap["rss.xml"].Content)
+
+	for _, expected := range []string{
+		`xmlns:dc="http://purl.org/dc/elements/1.1/"`,
+		"<author>jane@example.com (Jane)</author>",
+		"<dc:creator>Site Team</dc:creator>",
+	} {
+		if !strings.Contains(rss, expected) {
+			t.Error("Expected", expected, "in", rss)
+		}
+	}
+	if strings.Contains(rss, "<author>Site Team</author>") {
+		t.Error("Expected no author element for a name in", rss)
+	}
+
+	atom := string(fileMap["atom.xml"].Content)
+
+	if !strings.Contains(atom, "<updated>2020-01-02T00:00:00Z</updated>") {
+		t.Error("Expected the feed to be updated with its newest entry in", atom)
+	}
+}
+
+func TestFeedEmpty(t *testing.T) {
+	oldTimeNow := timeNow
+	defer func() { timeNow = oldTimeNow }()
+
+	timeNow = func() time.Time {
+		return time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	}
+
+	fileMap := gosnap.FileMapType{"about.html": newPage(gosnap.FrontmatterValueType{})}
+
+	if err := Feed(FeedConfig{Collection: Collection{Name: "posts"}, BaseURL: "https://example.com"})(fileMap); err != nil {
+		t.Fatal("Feed errored unexpectedly:", err)
+	}
+
+	// a feed without entries was updated when it was built
+	atom := string(fileMap["atom.xml"].Content)
+
+	if !strings.Contains(atom, "<updated>2021-03-04T05:06:07Z</updated>") {
+		t.Error("Expected the build time as updated in", atom)
+	}
+	if rss := string(fileMap["rss.xml"].Content); !strings.Contains(rss, "<lastBuildDate>Thu, 04 Mar 2021 05:06:07 +0000</lastBuildDate>") {
+		t.Error("Expected the build time as lastBuildDate in", rss)
+	}
+}
+
+func TestFeedUndated(t *testing.T) {
+	source, err := ioutil.TempDir("", "gosnap-feed")
+	if err != nil {
+		t.Fatal("Could not create source directory:", err)
+	}
+	defer os.RemoveAll(source)
+
+	modified := time.Date(2019, 8, 7, 6, 5, 4, 0, time.UTC)
+
+	for _, name := range []string{"a.html", "b.html"} {
+		filePath := filepath.Join(source, "posts", name)
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal("Could not create posts directory:", err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte("---\ntitle: "+name+"\n---\npost"), 0644); err != nil {
+			t.Fatal("Could not write source file:", err)
+		}
+		if err := os.Chtimes(filePath, modified, modified); err != nil {
+			t.Fatal("Could not set modification time:", err)
+		}
+	}
+
+	feed := Feed(FeedConfig{Collection: Collection{Name: "posts", Pattern: "posts/*"}, BaseURL: "https://example.com"})
+	var previous string
+
+	// building twice gives the same feeds, dated by the sources
+	for i := 0; i < 2; i++ {
+		site := gosnap.GoSnap{Source: source, Logger: log.New(ioutil.Discard, "", 0)}
+
+		if err := site.Read(); err != nil {
+			t.Fatal("Read errored unexpectedly:", err)
+		}
+		if err := gosnap.Run(site.FileMap, []gosnap.Plugin{feed}); err != nil {
+			t.Fatal("Run errored unexpectedly:", err)
+		}
+
+		rss := string(site.FileMap["rss.xml"].Content)
+
+		if strings.Count(rss, "<pubDate>Wed, 07 Aug 2019 06:05:04 +0000</pubDate>") != 2 {
+			t.Error("Expected the modification time of the sources as pubDate in", rss)
+		}
+		if i > 0 && rss != previous {
+			t.Error("Expected the same feed from both builds, instead got", previous, "and", rss)
+		}
+
+		previous = rss
+	}
+}
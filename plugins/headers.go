@@ -0,0 +1,209 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// HeaderFiles says where to put the header configuration for each kind of server, a kind is left out if its
+// path is empty. See DefaultHeaderFiles.
+type HeaderFiles struct {
+	// Netlify style _headers file, which Cloudflare Pages understands as well
+	Netlify string
+	// nginx configuration with a location block for every file, to include in a server block. Remember that
+	// add_header in a location replaces every add_header of the server block for that location.
+	Nginx string
+	// Apache .htaccess using mod_headers
+	Apache string
+}
+
+var DefaultHeaderFiles = HeaderFiles{Netlify: "_headers", Nginx: "headers.nginx.conf", Apache: ".htaccess"}
+
+// headers web servers already work out on their own from the file they serve
+var serverHeaders = map[string]bool{
+	"Content-Length": true,
+	"Content-Md5":    true,
+	"Last-Modified":  true,
+}
+
+// the headers of one file which a server needs to be told about
+type fileHeaders struct {
+	urlPath string
+	header  http.Header
+	keys    []string
+}
+
+func configurableHeaders(fileMap gosnap.FileMapType, skip map[string]bool) ([]fileHeaders, error) {
+	configured := []fileHeaders{}
+
+	for _, filePath := range sortedPaths(fileMap) {
+		if skip[filePath] {
+			continue
+		}
+
+		header := http.Header{}
+
+		for key, values := range fileMap[filePath].Headers() {
+			if serverHeaders[http.CanonicalHeaderKey(key)] {
+				continue
+			}
+
+			for _, value := range values {
+				// plugins can set anything, and a line break would be a line of configuration of its own
+				if err := gosnap.CheckHeader(key, value); err != nil {
+					return nil, errors.Wrapf(err, "Could not configure headers of %v", filePath)
+				}
+
+				if value != "" {
+					header.Add(key, value)
+				}
+			}
+		}
+
+		// servers guess the Content-Type from the extension just like gosnap does, so it only needs to be
+		// passed on when something changed it
+		if header.Get("Content-Type") == mime.TypeByExtension(path.Ext(filePath)) {
+			header.Del("Content-Type")
+		}
+
+		if len(header) == 0 {
+			continue
+		}
+
+		keys := make([]string, 0, len(header))
+		for key := range header {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		configured = append(configured, fileHeaders{urlPath: "/" + filePath, header: header, keys: keys})
+	}
+
+	return configured, nil
+}
+
+// paths a file is requested at, directories are requested for their index.html
+func requestPaths(urlPath string) []string {
+	if path.Base(urlPath) == "index.html" {
+		return []string{strings.TrimSuffix(urlPath, "index.html"), urlPath}
+	}
+
+	return []string{urlPath}
+}
+
+func netlifyHeaders(configured []fileHeaders) []byte {
+	var buffer bytes.Buffer
+
+	for _, file := range configured {
+		for _, requestPath := range requestPaths(file.urlPath) {
+			fmt.Fprintf(&buffer, "%v\n", requestPath)
+
+			for _, key := range file.keys {
+				for _, value := range file.header[key] {
+					fmt.Fprintf(&buffer, "  %v: %v\n", key, value)
+				}
+			}
+		}
+	}
+
+	return buffer.Bytes()
+}
+
+func nginxQuote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// the index directive sends requests for directories on to index.html, so one location per file is enough
+func nginxHeaders(configured []fileHeaders) []byte {
+	var buffer bytes.Buffer
+
+	for _, file := range configured {
+		fmt.Fprintf(&buffer, "location = %v {\n", nginxQuote(file.urlPath))
+
+		for _, key := range file.keys {
+			for _, value := range file.header[key] {
+				// add_header can not replace the Content-Type nginx sets
+				if key == "Content-Type" {
+					fmt.Fprintf(&buffer, "    types { }\n    default_type %v;\n", nginxQuote(value))
+				} else {
+					fmt.Fprintf(&buffer, "    add_header %v %v;\n", key, nginxQuote(value))
+				}
+			}
+		}
+
+		buffer.WriteString("}\n\n")
+	}
+
+	return buffer.Bytes()
+}
+
+func apacheQuote(text string, quote string) string {
+	return quote + strings.NewReplacer(`\`, `\\`, quote, `\`+quote).Replace(text) + quote
+}
+
+func apacheHeaders(configured []fileHeaders) []byte {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("<IfModule mod_headers.c>\n")
+
+	for _, file := range configured {
+		requestPaths := requestPaths(file.urlPath)
+		for i, requestPath := range requestPaths {
+			requestPaths[i] = apacheQuote(requestPath, "'")
+		}
+
+		fmt.Fprintf(&buffer, "<If \"%%{REQUEST_URI} in { %v }\">\n", strings.Join(requestPaths, ", "))
+
+		for _, key := range file.keys {
+			for i, value := range file.header[key] {
+				// the first value replaces whatever Apache would have sent, the rest are added to it
+				action := "set"
+				if i > 0 {
+					action = "add"
+				}
+
+				fmt.Fprintf(&buffer, "    Header %v %v %v\n", action, key, apacheQuote(value, `"`))
+			}
+		}
+
+		buffer.WriteString("</If>\n")
+	}
+
+	buffer.WriteString("</IfModule>\n")
+
+	return buffer.Bytes()
+}
+
+// ServerHeaders writes the headers of every file into configuration for the web servers in files, so that
+// a Cache-Control or Content-Type set by frontmatter or a plugin is what the server actually sends. Headers the
+// server works out itself, like Content-Length, are left out. Run it after every plugin which changes headers.
+func ServerHeaders(files HeaderFiles) gosnap.Plugin {
+	return gosnap.Named("plugins.ServerHeaders", func(fileMap gosnap.FileMapType) error {
+		skip := map[string]bool{files.Netlify: true, files.Nginx: true, files.Apache: true}
+		configured, err := configurableHeaders(fileMap, skip)
+
+		if err != nil {
+			return err
+		}
+
+		if files.Netlify != "" {
+			fileMap[files.Netlify] = gosnap.NewFile(files.Netlify, netlifyHeaders(configured))
+		}
+		if files.Nginx != "" {
+			fileMap[files.Nginx] = gosnap.NewFile(files.Nginx, nginxHeaders(configured))
+		}
+		if files.Apache != "" {
+			fileMap[files.Apache] = gosnap.NewFile(files.Apache, apacheHeaders(configured))
+		}
+
+		return nil
+	})
+}
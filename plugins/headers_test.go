@@ -0,0 +1,141 @@
+package plugins
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+// a page with headers as a plugin would have set them
+func withHeaders(header http.Header) *gosnap.GoSnapFile {
+	file := newPage(gosnap.FrontmatterValueType{})
+
+	for key, values := range header {
+		file.Headers()[key] = values
+	}
+
+	return file
+}
+
+type serverHeadersStruct struct {
+	fileMap         gosnap.FileMapType
+	expectedNetlify string
+	expectedNginx   string
+	expectedApache  string
+	expectedError   bool
+}
+
+var serverHeadersTests = []serverHeadersStruct{
+	{
+		gosnap.FileMapType{
+			"blog/index.html": withHeaders(http.Header{
+				"Cache-Control":  {"max-age=60"},
+				"Link":           {"</a.css>; rel=preload", "</b.js>; rel=preload"},
+				"Content-Length": {"4"},
+			}),
+			"style.css": withHeaders(http.Header{
+				"Content-Type": {"text/plain"},
+				"X-Quote":      {`say "hi"`},
+			}),
+			// servers work out all of these on their own
+			"plain.html": withHeaders(http.Header{
+				"Content-Type":  {"text/html; charset=utf-8"},
+				"Last-Modified": {"Wed, 01 Jan 2020 00:00:00 GMT"},
+			}),
+		},
+		"/blog/\n" +
+			"  Cache-Control: max-age=60\n" +
+			"  Link: </a.css>; rel=preload\n" +
+			"  Link: </b.js>; rel=preload\n" +
+			"/blog/index.html\n" +
+			"  Cache-Control: max-age=60\n" +
+			"  Link: </a.css>; rel=preload\n" +
+			"  Link: </b.js>; rel=preload\n" +
+			"/style.css\n" +
+			"  Content-Type: text/plain\n" +
+			"  X-Quote: say \"hi\"\n",
+		"location = \"/blog/index.html\" {\n" +
+			"    add_header Cache-Control \"max-age=60\";\n" +
+			"    add_header Link \"</a.css>; rel=preload\";\n" +
+			"    add_header Link \"</b.js>; rel=preload\";\n" +
+			"}\n\n" +
+			"location = \"/style.css\" {\n" +
+			"    types { }\n    default_type \"text/plain\";\n" +
+			"    add_header X-Quote \"say \\\"hi\\\"\";\n" +
+			"}\n\n",
+		"<IfModule mod_headers.c>\n" +
+			"<If \"%{REQUEST_URI} in { '/blog/', '/blog/index.html' }\">\n" +
+			"    Header set Cache-Control \"max-age=60\"\n" +
+			"    Header set Link \"</a.css>; rel=preload\"\n" +
+			"    Header add Link \"</b.js>; rel=preload\"\n" +
+			"</If>\n" +
+			"<If \"%{REQUEST_URI} in { '/style.css' }\">\n" +
+			"    Header set Content-Type \"text/plain\"\n" +
+			"    Header set X-Quote \"say \\\"hi\\\"\"\n" +
+			"</If>\n" +
+			"</IfModule>\n",
+		false,
+	},
+	// a line break would be a line of configuration of its own
+	{
+		gosnap.FileMapType{"a.html": withHeaders(http.Header{"X-Lines": {"first\nadd_header X-Injected yes;"}})},
+		"",
+		"",
+		"",
+		true,
+	},
+	{
+		gosnap.FileMapType{"a.html": withHeaders(http.Header{"X-Lines": {"first\r"}})},
+		"",
+		"",
+		"",
+		true,
+	},
+	{
+		gosnap.FileMapType{"a.html": withHeaders(http.Header{"X Spaced {": {"value"}})},
+		"",
+		"",
+		"",
+		true,
+	},
+}
+
+func TestServerHeaders(t *testing.T) {
+	for i, test := range serverHeadersTests {
+		err := ServerHeaders(DefaultHeaderFiles)(test.fileMap)
+
+		if (err != nil) != test.expectedError {
+			t.Error(
+				"Expected error", test.expectedError,
+				"in case", i,
+				"instead got", err,
+			)
+		}
+		if test.expectedError {
+			if _, exists := test.fileMap[DefaultHeaderFiles.Netlify]; exists {
+				t.Error("Expected no header files when headers are invalid in case", i)
+			}
+			continue
+		}
+
+		for filePath, expected := range map[string]string{
+			DefaultHeaderFiles.Netlify: test.expectedNetlify,
+			DefaultHeaderFiles.Nginx:   test.expectedNginx,
+			DefaultHeaderFiles.Apache:  test.expectedApache,
+		} {
+			content := ""
+			if file, exists := test.fileMap[filePath]; exists {
+				content = string(file.Content)
+			}
+
+			if content != expected {
+				t.Error(
+					"Expected", filePath, expected,
+					"instead got", content,
+					"in case", i,
+				)
+			}
+		}
+	}
+}
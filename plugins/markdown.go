@@ -0,0 +1,79 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+	"github.com/russross/blackfriday"
+)
+
+var markdownExtensions = []string{".md", ".markdown"}
+
+func isMarkdown(filePath string) bool {
+	extension := strings.ToLower(path.Ext(filePath))
+
+	for _, markdownExtension := range markdownExtensions {
+		if extension == markdownExtension {
+			return true
+		}
+	}
+
+	return false
+}
+
+var templateAction = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+
+// markdown would escape the quotes in actions like {{template "footer.html" .}} and smartypants would curl them,
+// so in templates they are swapped for placeholders which markdown leaves alone until it is done
+func markdownTemplate(content []byte) []byte {
+	actions := [][]byte{}
+
+	protected := templateAction.ReplaceAllFunc(content, func(action []byte) []byte {
+		actions = append(actions, action)
+
+		return []byte(fmt.Sprintf("GOSNAPTEMPLATEACTION%vEND", len(actions)-1))
+	})
+
+	converted := blackfriday.MarkdownCommon(protected)
+
+	for i := len(actions) - 1; i >= 0; i-- {
+		converted = bytes.Replace(converted, []byte(fmt.Sprintf("GOSNAPTEMPLATEACTION%vEND", i)), actions[i], -1)
+	}
+
+	return converted
+}
+
+// Markdown converts markdown files to html and renames them from foo.md to foo.html,
+// frontmatter is kept so that Render can put the result into a layout afterwards
+func Markdown(fileMap gosnap.FileMapType) error {
+	for filePath, file := range fileMap {
+		if file.Passthrough || !isMarkdown(filePath) {
+			continue
+		}
+
+		htmlPath := strings.TrimSuffix(filePath, path.Ext(filePath)) + ".html"
+
+		if _, exists := fileMap[htmlPath]; exists {
+			return errors.Errorf("Could not convert %v to html since %v already exists", filePath, htmlPath)
+		}
+
+		if isTemplate(file) {
+			file.Content = markdownTemplate(file.Content)
+		} else {
+			file.Content = blackfriday.MarkdownCommon(file.Content)
+		}
+		// serve the converted file as html while keeping the rest of its headers
+		file.Headers().Set("Content-Type", mime.TypeByExtension(".html"))
+
+		delete(fileMap, filePath)
+		fileMap[htmlPath] = file
+	}
+
+	return nil
+}
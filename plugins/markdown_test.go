@@ -0,0 +1,68 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+func TestMarkdown(t *testing.T) {
+	post := gosnap.NewFile("posts/a.md", []byte("# Hello \"there\""))
+	post.Data = gosnap.FrontmatterValueType{"title": "Hello"}
+	fileMap := gosnap.FileMapType{"posts/a.md": post, "style.css": gosnap.NewFile("style.css", []byte("a{}"))}
+
+	if err := Markdown(fileMap); err != nil {
+		t.Fatal("Markdown errored unexpectedly:", err)
+	}
+
+	if _, exists := fileMap["posts/a.md"]; exists {
+		t.Error("Expected posts/a.md to be renamed")
+	}
+	if fileMap["posts/a.html"] != post {
+		t.Fatal("Expected posts/a.md to be renamed to posts/a.html")
+	}
+	if string(post.Content) != "<h1>Hello &ldquo;there&rdquo;</h1>\n" {
+		t.Error("Expected markdown to be converted, instead got", string(post.Content))
+	}
+	if post.Headers().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Error("Expected the converted file to be served as html, instead got", post.Headers().Get("Content-Type"))
+	}
+	if post.Data["title"] != "Hello" {
+		t.Error("Expected frontmatter to be kept, instead got", post.Data)
+	}
+	if string(fileMap["style.css"].Content) != "a{}" {
+		t.Error("Expected other files to be left alone, instead got", string(fileMap["style.css"].Content))
+	}
+}
+
+func TestMarkdownCollision(t *testing.T) {
+	for i, fileMap := range []gosnap.FileMapType{
+		{"a.md": gosnap.NewFile("a.md", []byte("a")), "a.markdown": gosnap.NewFile("a.markdown", []byte("a"))},
+		{"a.md": gosnap.NewFile("a.md", []byte("a")), "a.html": gosnap.NewFile("a.html", []byte("a"))},
+	} {
+		if err := Markdown(fileMap); err == nil {
+			t.Error("Expected two files ending up at a.html to error in case", i)
+		}
+	}
+}
+
+func TestMarkdownTemplate(t *testing.T) {
+	page := newTemplate("# {{.title}}\n\nSee \"below\"\n\n{{ template \"partials/footer.html\" . }}\n", gosnap.FrontmatterValueType{"title": "Hi"})
+	fileMap := gosnap.FileMapType{
+		"page.md": page,
+		"partials/footer.html": &gosnap.GoSnapFile{
+			Content: []byte(`<footer>{{.title}}</footer>`),
+			Data:    gosnap.FrontmatterValueType{"partial": true},
+		},
+	}
+
+	if err := gosnap.Run(fileMap, []gosnap.Plugin{Markdown, Render}); err != nil {
+		t.Fatal("Expected actions in markdown templates to survive, instead got", err)
+	}
+
+	expected := "<h1>Hi</h1>\n\n<p>See &ldquo;below&rdquo;</p>\n\n<p><footer>Hi</footer></p>\n"
+
+	if string(fileMap["page.html"].Content) != expected {
+		t.Error("Expected", expected, "instead got", string(fileMap["page.html"].Content))
+	}
+}
@@ -28,19 +28,17 @@ func setup() *minify.M {
 
 var minifier = setup()
 
-func minifyType(mimetype string, suffix string) gosnap.Plugin {
-	return func(fileMap gosnap.FileMapType) error {
-		for filePath, file := range fileMap {
-			if strings.HasSuffix(filePath, suffix) {
-				if val, exists := file.Data["minify"]; !exists || val == true {
-					minified, err := minifier.Bytes(mimetype, file.Content)
-
-					if err != nil {
-						return errors.Wrapf(err, "Could not minify file %v", filePath)
-					}
-
-					file.Content = minified
+func minifyType(mimetype string, suffix string) gosnap.FilePlugin {
+	return func(filePath string, file *gosnap.GoSnapFile) error {
+		if strings.HasSuffix(filePath, suffix) {
+			if val, exists := file.Data["minify"]; !exists || val == true {
+				minified, err := minifier.Bytes(mimetype, file.Content)
+
+				if err != nil {
+					return errors.Wrapf(err, "Could not minify file %v", filePath)
 				}
+
+				file.Content = minified
 			}
 		}
 
@@ -48,8 +46,15 @@ func minifyType(mimetype string, suffix string) gosnap.Plugin {
 	}
 }
 
-var MinifyCSS = minifyType("text/css", ".css")
-var MinifyHTML = minifyType("text/html", ".html")
-var MinifyJS = minifyType("text/javascript", ".js")
-var MinifyJSON = minifyType("text/.json", ".json")
-var MinifyXML = minifyType("text/.xml", ".xml")
+// per file versions which can be combined into a single parallel step with gosnap.Each
+var MinifyCSSFile = gosnap.NamedFile("plugins.MinifyCSSFile", minifyType("text/css", ".css"))
+var MinifyHTMLFile = gosnap.NamedFile("plugins.MinifyHTMLFile", minifyType("text/html", ".html"))
+var MinifyJSFile = gosnap.NamedFile("plugins.MinifyJSFile", minifyType("text/javascript", ".js"))
+var MinifyJSONFile = gosnap.NamedFile("plugins.MinifyJSONFile", minifyType("text/.json", ".json"))
+var MinifyXMLFile = gosnap.NamedFile("plugins.MinifyXMLFile", minifyType("text/.xml", ".xml"))
+
+var MinifyCSS = gosnap.Each(MinifyCSSFile)
+var MinifyHTML = gosnap.Each(MinifyHTMLFile)
+var MinifyJS = gosnap.Each(MinifyJSFile)
+var MinifyJSON = gosnap.Each(MinifyJSONFile)
+var MinifyXML = gosnap.Each(MinifyXMLFile)
@@ -0,0 +1,108 @@
+package plugins
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// Pagination splits the files of a collection over several pages which are all rendered from one template file
+type Pagination struct {
+	Collection Collection
+	PerPage    int
+	// path of the file in the file map used for every page, it is also the first page
+	Template string
+	// path of every page after the first with :num for the page number, for example "blog/page/:num/index.html"
+	Path string
+}
+
+// One page of a pagination as seen by templates
+type Page struct {
+	Number int
+	Path   string
+	Items  []*CollectionItem
+	// nil on the first and last page
+	Previous *Page
+	Next     *Page
+	// every page, for numbered links
+	Pages []*Page
+}
+
+// Paginate adds a file for every page after the first, all of them copies of the template file with their
+// Page as "pagination" in their frontmatter, for example {{range .pagination.Items}}
+func Paginate(pagination Pagination) gosnap.Plugin {
+	return gosnap.Named("plugins.Paginate", func(fileMap gosnap.FileMapType) error {
+		template, exists := fileMap[pagination.Template]
+
+		if !exists {
+			return errors.Errorf("Pagination template %v does not exist", pagination.Template)
+		}
+		if pagination.PerPage < 1 {
+			return errors.Errorf("Pagination for %v needs at least one item per page", pagination.Template)
+		}
+		if !strings.Contains(pagination.Path, ":num") {
+			return errors.Errorf("Pagination path %v for %v needs to contain :num", pagination.Path, pagination.Template)
+		}
+
+		// the template and the pages made from it are not items of their own listing
+		pagePath := regexp.MustCompile("^" + strings.Replace(regexp.QuoteMeta(pagination.Path), ":num", "[0-9]+", -1) + "$")
+		items := []*CollectionItem{}
+
+		for _, item := range pagination.Collection.gather(fileMap, sortedPaths(fileMap)) {
+			if item.Path != pagination.Template && !pagePath.MatchString(item.Path) {
+				items = append(items, item)
+			}
+		}
+
+		// there is always a first page even if it has nothing on it
+		count := (len(items) + pagination.PerPage - 1) / pagination.PerPage
+		if count == 0 {
+			count = 1
+		}
+
+		pages := make([]*Page, count)
+
+		for i := range pages {
+			start := i * pagination.PerPage
+			end := start + pagination.PerPage
+			if end > len(items) {
+				end = len(items)
+			}
+
+			pages[i] = &Page{Number: i + 1, Items: items[start:end], Pages: pages}
+
+			if i == 0 {
+				pages[i].Path = pagination.Template
+			} else {
+				pages[i].Path = strings.Replace(pagination.Path, ":num", strconv.Itoa(i+1), -1)
+				pages[i].Previous = pages[i-1]
+				pages[i-1].Next = pages[i]
+			}
+		}
+
+		for _, page := range pages[1:] {
+			if _, exists := fileMap[page.Path]; exists {
+				return errors.Errorf("Could not add page %v for %v since the file already exists", page.Path, pagination.Template)
+			}
+		}
+
+		for _, page := range pages {
+			file := template
+			if page.Number > 1 {
+				file = template.Copy()
+				fileMap[page.Path] = file
+			}
+
+			if file.Data == nil {
+				file.Data = make(gosnap.FrontmatterValueType)
+			}
+
+			file.Data["pagination"] = page
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+package plugins
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+// a blog index and count posts
+func blog(count int) gosnap.FileMapType {
+	fileMap := gosnap.FileMapType{"blog/index.html": newPage(gosnap.FrontmatterValueType{})}
+
+	for i := 1; i <= count; i++ {
+		fileMap[fmt.Sprintf("blog/post%v.html", i)] = newPage(gosnap.FrontmatterValueType{})
+	}
+
+	return fileMap
+}
+
+type paginateStruct struct {
+	fileMap    gosnap.FileMapType
+	pagination Pagination
+	// paths of the items on every page by the path of the page
+	expected      map[string][]string
+	expectedError bool
+}
+
+var blogPosts = Collection{Name: "posts", Pattern: "blog/*.html"}
+
+var paginateTests = []paginateStruct{
+	{
+		blog(5),
+		Pagination{Collection: blogPosts, PerPage: 2, Template: "blog/index.html", Path: "blog/page/:num/index.html"},
+		map[string][]string{
+			"blog/index.html":        {"blog/post1.html", "blog/post2.html"},
+			"blog/page/2/index.html": {"blog/post3.html", "blog/post4.html"},
+			"blog/page/3/index.html": {"blog/post5.html"},
+		},
+		false,
+	},
+	{
+		blog(4),
+		Pagination{Collection: blogPosts, PerPage: 2, Template: "blog/index.html", Path: "blog/page:num.html"},
+		map[string][]string{
+			"blog/index.html": {"blog/post1.html", "blog/post2.html"},
+			"blog/page2.html": {"blog/post3.html", "blog/post4.html"},
+		},
+		false,
+	},
+	// there is always a first page
+	{
+		blog(0),
+		Pagination{Collection: blogPosts, PerPage: 2, Template: "blog/index.html", Path: "blog/page/:num/index.html"},
+		map[string][]string{"blog/index.html": {}},
+		false,
+	},
+	{
+		blog(3),
+		Pagination{Collection: blogPosts, PerPage: 2, Template: "blog/index.html", Path: "blog/page/index.html"},
+		nil,
+		true,
+	},
+	{
+		gosnap.FileMapType{
+			"blog/index.html":        newPage(gosnap.FrontmatterValueType{}),
+			"blog/a.html":            newPage(gosnap.FrontmatterValueType{"collection": "posts"}),
+			"blog/b.html":            newPage(gosnap.FrontmatterValueType{"collection": "posts"}),
+			"blog/page/2/index.html": newPage(gosnap.FrontmatterValueType{}),
+		},
+		Pagination{Collection: Collection{Name: "posts"}, PerPage: 1, Template: "blog/index.html", Path: "blog/page/:num/index.html"},
+		nil,
+		true,
+	},
+	{
+		blog(3),
+		Pagination{Collection: blogPosts, PerPage: 2, Template: "missing.html", Path: "page/:num/index.html"},
+		nil,
+		true,
+	},
+	{
+		blog(3),
+		Pagination{Collection: blogPosts, PerPage: 0, Template: "blog/index.html", Path: "blog/page/:num/index.html"},
+		nil,
+		true,
+	},
+}
+
+func TestPaginate(t *testing.T) {
+	for i, test := range paginateTests {
+		err := Paginate(test.pagination)(test.fileMap)
+
+		if (err != nil) != test.expectedError {
+			t.Error(
+				"Expected error", test.expectedError,
+				"in case", i,
+				"instead got", err,
+			)
+		}
+		if test.expected == nil {
+			continue
+		}
+
+		pages := make(map[string][]string)
+
+		for filePath, file := range test.fileMap {
+			page, ok := file.Data["pagination"].(*Page)
+			if !ok {
+				continue
+			}
+
+			if page.Path != filePath || len(page.Pages) != len(test.expected) {
+				t.Error("Expected page", filePath, "to know its path and every page, instead got", page, "in case", i)
+			}
+
+			pages[filePath] = []string{}
+			for _, item := range page.Items {
+				pages[filePath] = append(pages[filePath], item.Path)
+			}
+		}
+
+		if !reflect.DeepEqual(pages, test.expected) {
+			t.Error(
+				"Expected pages", test.expected,
+				"instead got", pages,
+				"in case", i,
+			)
+		}
+	}
+}
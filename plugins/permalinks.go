@@ -0,0 +1,176 @@
+package plugins
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// Permalink moves the files it matches to a path built from Pattern, for example ":collection/:year/:slug/".
+// Pattern can use :year, :month and :day from the date frontmatter, :slug (the slug frontmatter or else
+// the file name), :basename, :dir for the directory of the file, or any other frontmatter key.
+// Patterns ending in / get index.html appended so that the file is served at a clean url.
+type Permalink struct {
+	Pattern string
+	// glob for the paths of the files to move, matched against the whole path or just the file name
+	Match string
+	// only move files with this collection in their frontmatter
+	Collection string
+}
+
+var notSlug = regexp.MustCompile("[^a-z0-9]+")
+
+// turns any text into something that looks nice in an url
+func slugify(text string) string {
+	return strings.Trim(notSlug.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
+func (permalink Permalink) matches(filePath string, file *gosnap.GoSnapFile) bool {
+	if permalink.Collection != "" && !(Collection{Name: permalink.Collection}).contains(filePath, file) {
+		return false
+	}
+
+	match := permalink.Match
+	if match == "" {
+		if permalink.Collection != "" {
+			return true
+		}
+
+		match = "*.html"
+	}
+
+	matched, _ := path.Match(match, filePath)
+	if !matched {
+		matched, _ = path.Match(match, path.Base(filePath))
+	}
+
+	return matched
+}
+
+var placeholder = regexp.MustCompile(":[a-zA-Z_]+")
+
+// builds the new path of a file from the pattern
+func (permalink Permalink) expand(filePath string, file *gosnap.GoSnapFile) (string, error) {
+	basename := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	date, dateErr := file.Data.Time("date")
+	var missing []string
+
+	expanded := placeholder.ReplaceAllStringFunc(permalink.Pattern, func(token string) string {
+		key := token[1:]
+
+		switch key {
+		case "year", "month", "day":
+			if dateErr != nil {
+				missing = append(missing, "date")
+				return token
+			}
+
+			switch key {
+			case "year":
+				return date.Format("2006")
+			case "month":
+				return date.Format("01")
+			}
+			return date.Format("02")
+		case "basename":
+			return basename
+		case "dir":
+			return path.Dir(filePath)
+		case "slug":
+			if slug, err := file.Data.String("slug"); err == nil && slug != "" {
+				return slugify(slug)
+			}
+			return slugify(basename)
+		}
+
+		value, exists := file.Data[key]
+		if !exists || value == nil {
+			missing = append(missing, key)
+			return token
+		}
+
+		return slugify(fmt.Sprint(value))
+	})
+
+	if len(missing) > 0 {
+		return "", errors.Errorf("Could not build permalink for %v, frontmatter is missing %v", filePath, strings.Join(missing, ", "))
+	}
+
+	if strings.HasSuffix(expanded, "/") {
+		expanded += "index.html"
+	}
+
+	return strings.TrimPrefix(path.Clean("/"+expanded), "/"), nil
+}
+
+// Permalinks moves files according to the first Permalink which matches them. A file can also choose its own
+// path with "permalink: some/path/" in its frontmatter, or stay where it is with "permalink: false".
+// It is an error for two files to end up at the same path.
+func Permalinks(permalinks ...Permalink) gosnap.Plugin {
+	return gosnap.Named("plugins.Permalinks", func(fileMap gosnap.FileMapType) error {
+		filePaths := sortedPaths(fileMap)
+
+		moves := make(map[string]string)
+
+		for _, filePath := range filePaths {
+			file := fileMap[filePath]
+
+			switch own := file.Data["permalink"].(type) {
+			case bool:
+				if !own {
+					continue
+				}
+			case string:
+				moved, err := Permalink{Pattern: own}.expand(filePath, file)
+				if err != nil {
+					return err
+				}
+
+				moves[filePath] = moved
+				continue
+			}
+
+			for _, permalink := range permalinks {
+				if permalink.matches(filePath, file) {
+					moved, err := permalink.expand(filePath, file)
+					if err != nil {
+						return err
+					}
+
+					moves[filePath] = moved
+					break
+				}
+			}
+		}
+
+		// every file which is not moved keeps its path
+		destinations := make(map[string]string, len(fileMap))
+		for _, filePath := range filePaths {
+			destination, moved := moves[filePath]
+			if !moved {
+				destination = filePath
+			}
+
+			if other, taken := destinations[destination]; taken {
+				return errors.Errorf("Permalink conflict, both %v and %v would be written to %v", other, filePath, destination)
+			}
+
+			destinations[destination] = filePath
+		}
+
+		moved := make(gosnap.FileMapType, len(moves))
+		for filePath, destination := range moves {
+			moved[destination] = fileMap[filePath]
+			delete(fileMap, filePath)
+		}
+		for destination, file := range moved {
+			fileMap[destination] = file
+		}
+
+		return nil
+	})
+}
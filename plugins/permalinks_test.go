@@ -0,0 +1,75 @@
+package plugins
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+func newPage(data gosnap.FrontmatterValueType) *gosnap.GoSnapFile {
+	return &gosnap.GoSnapFile{Content: []byte("page"), Data: data}
+}
+
+type permalinksStruct struct {
+	fileMap       gosnap.FileMapType
+	permalinks    []Permalink
+	expected      []string
+	expectedError bool
+}
+
+var permalinksTests = []permalinksStruct{
+	{
+		gosnap.FileMapType{
+			"posts/a.html": newPage(gosnap.FrontmatterValueType{"title": "First Post"}),
+			"posts/b.html": newPage(gosnap.FrontmatterValueType{"permalink": "hello/"}),
+			"posts/c.html": newPage(gosnap.FrontmatterValueType{"permalink": false}),
+		},
+		[]Permalink{{Pattern: ":dir/:title/"}},
+		[]string{"hello/index.html", "posts/c.html", "posts/first-post/index.html"},
+		false,
+	},
+	// two files expanding to the same path
+	{
+		gosnap.FileMapType{
+			"2020/a.html": newPage(gosnap.FrontmatterValueType{"slug": "same"}),
+			"2021/a.html": newPage(gosnap.FrontmatterValueType{"slug": "same"}),
+		},
+		[]Permalink{{Pattern: ":slug/"}},
+		[]string{"2020/a.html", "2021/a.html"},
+		true,
+	},
+	// a file moved onto one which stays where it is
+	{
+		gosnap.FileMapType{
+			"about.html":       newPage(gosnap.FrontmatterValueType{"permalink": "about/"}),
+			"about/index.html": newPage(gosnap.FrontmatterValueType{"permalink": false}),
+		},
+		[]Permalink{},
+		[]string{"about.html", "about/index.html"},
+		true,
+	},
+}
+
+func TestPermalinks(t *testing.T) {
+	for i, test := range permalinksTests {
+		err := Permalinks(test.permalinks...)(test.fileMap)
+
+		if (err != nil) != test.expectedError {
+			t.Error(
+				"Expected error", test.expectedError,
+				"in case", i,
+				"instead got", err,
+			)
+		}
+
+		// nothing is moved when there is a conflict
+		if filePaths := sortedPaths(test.fileMap); !reflect.DeepEqual(filePaths, test.expected) {
+			t.Error(
+				"Expected", test.expected,
+				"instead got", filePaths,
+				"in case", i,
+			)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package plugins
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// PrecompressConfig configures which files Precompress writes compressed copies of
+type PrecompressConfig struct {
+	// write .br files as well as .gz ones, which needs gosnap built with the brotli build tag
+	Brotli bool
+	// a compressed copy is only kept if it is at most this fraction of the size of the file, defaults to 0.9
+	MaxRatio float64
+	// files smaller than this many bytes are not worth compressing
+	MinSize int
+	// media types to compress, a type ending in / matches everything under it, defaults to CompressibleTypes
+	Types []string
+}
+
+// media types which compress well, images other than svg and fonts like woff2 are compressed already
+var CompressibleTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/xml",
+	"application/rss+xml",
+	"application/atom+xml",
+	"image/svg+xml",
+	"image/x-icon",
+	"font/ttf",
+	"font/otf",
+}
+
+// one way of compressing files
+type encoding struct {
+	name      string
+	extension string
+	compress  func(io.Writer) io.WriteCloser
+}
+
+var gzipEncoding = encoding{"gzip", ".gz", func(w io.Writer) io.WriteCloser {
+	writer, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	return writer
+}}
+
+func (enc encoding) apply(content []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := enc.compress(&buffer)
+
+	if _, err := writer.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func compressible(types []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return false
+	}
+
+	for _, compressibleType := range types {
+		if mediaType == compressibleType || (strings.HasSuffix(compressibleType, "/") && strings.HasPrefix(mediaType, compressibleType)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Precompress adds compressed copies of files next to them, foo.css.gz and with Brotli foo.css.br, for servers
+// which send those instead of compressing on every request. The copies keep the headers of the file and get a
+// Content-Encoding. Run it last so that the copies match what is written out.
+func Precompress(config PrecompressConfig) gosnap.Plugin {
+	maxRatio := config.MaxRatio
+	if maxRatio <= 0 {
+		maxRatio = 0.9
+	}
+
+	types := config.Types
+	if types == nil {
+		types = CompressibleTypes
+	}
+
+	encodings := []encoding{gzipEncoding}
+	if config.Brotli && brotliEncoding != nil {
+		encodings = append(encodings, *brotliEncoding)
+	}
+
+	return gosnap.Named("plugins.Precompress", func(fileMap gosnap.FileMapType) error {
+		if config.Brotli && brotliEncoding == nil {
+			return errors.New("Brotli compression needs gosnap built with -tags brotli")
+		}
+
+		var lock sync.Mutex
+		compressed := make(map[string]*gosnap.GoSnapFile)
+
+		// compressing is slow so it is done in parallel, the copies are only added to fileMap afterwards
+		compress := func(filePath string, file *gosnap.GoSnapFile) error {
+			if file.Passthrough || len(file.Content) < config.MinSize || !compressible(types, file.Headers().Get("Content-Type")) {
+				return nil
+			}
+
+			for _, enc := range encodings {
+				// a compressed copy which came with the source is kept as it is
+				if _, exists := fileMap[filePath+enc.extension]; exists {
+					continue
+				}
+
+				content, err := enc.apply(file.Content)
+
+				if err != nil {
+					return errors.Wrapf(err, "Could not compress %v with %v", filePath, enc.name)
+				}
+
+				if float64(len(content)) > maxRatio*float64(len(file.Content)) {
+					continue
+				}
+
+				copied := gosnap.NewFile(filePath+enc.extension, content)
+				for key, values := range file.Headers() {
+					// the copy has its own content to describe
+					if key != "Content-Length" && key != "Content-Md5" {
+						copied.Headers()[key] = append([]string{}, values...)
+					}
+				}
+				copied.Headers().Set("Content-Encoding", enc.name)
+				if file.FileInfo != nil {
+					copied.FileInfo = gosnap.NewFileInfo(copied.FileInfo.Name(), int64(len(content)), file.FileInfo.Mode(), file.FileInfo.ModTime())
+				}
+
+				lock.Lock()
+				compressed[filePath+enc.extension] = copied
+				lock.Unlock()
+			}
+
+			return nil
+		}
+
+		if err := gosnap.Each(compress)(fileMap); err != nil {
+			return err
+		}
+
+		for filePath, file := range compressed {
+			fileMap[filePath] = file
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,15 @@
+//go:build brotli
+// +build brotli
+
+package plugins
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotli needs a newer Go than the rest of gosnap, so it is only built in with the brotli build tag
+var brotliEncoding = &encoding{"br", ".br", func(w io.Writer) io.WriteCloser {
+	return brotli.NewWriterLevel(w, brotli.BestCompression)
+}}
@@ -0,0 +1,7 @@
+//go:build !brotli
+// +build !brotli
+
+package plugins
+
+// without the brotli build tag Precompress only writes gzip copies, see precompress_brotli.go
+var brotliEncoding *encoding
@@ -0,0 +1,157 @@
+package plugins
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+var repetitiveCSS = []byte(strings.Repeat("body { color: red; }\n", 50))
+
+// random bytes do not compress at all
+func noise(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	return content
+}
+
+type precompressStruct struct {
+	config   PrecompressConfig
+	fileMap  gosnap.FileMapType
+	expected []string
+}
+
+var precompressTests = []precompressStruct{
+	{
+		PrecompressConfig{},
+		gosnap.FileMapType{
+			"a.css":   gosnap.NewFile("a.css", repetitiveCSS),
+			"b.js":    gosnap.NewFile("b.js", noise(1000)),
+			"c.png":   gosnap.NewFile("c.png", repetitiveCSS),
+			"d.json":  gosnap.NewFile("d.json", []byte(strings.Repeat("[1, 2, 3]", 50))),
+			"e.woff2": gosnap.NewFile("e.woff2", repetitiveCSS),
+		},
+		[]string{"a.css", "a.css.gz", "b.js", "c.png", "d.json", "d.json.gz", "e.woff2"},
+	},
+	// a copy has to save at least as much as the ratio asks for
+	{
+		PrecompressConfig{MaxRatio: 0.01},
+		gosnap.FileMapType{"a.css": gosnap.NewFile("a.css", repetitiveCSS)},
+		[]string{"a.css"},
+	},
+	{
+		PrecompressConfig{MinSize: 2000},
+		gosnap.FileMapType{
+			"a.css": gosnap.NewFile("a.css", repetitiveCSS),
+			"b.css": gosnap.NewFile("b.css", bytes.Repeat(repetitiveCSS, 2)),
+		},
+		[]string{"a.css", "b.css", "b.css.gz"},
+	},
+	{
+		PrecompressConfig{Types: []string{"text/css"}},
+		gosnap.FileMapType{
+			"a.css":  gosnap.NewFile("a.css", repetitiveCSS),
+			"b.html": gosnap.NewFile("b.html", repetitiveCSS),
+			"c.svg":  gosnap.NewFile("c.svg", repetitiveCSS),
+		},
+		[]string{"a.css", "a.css.gz", "b.html", "c.svg"},
+	},
+	{
+		PrecompressConfig{Types: []string{"text/"}},
+		gosnap.FileMapType{
+			"a.css":  gosnap.NewFile("a.css", repetitiveCSS),
+			"b.html": gosnap.NewFile("b.html", repetitiveCSS),
+			"c.svg":  gosnap.NewFile("c.svg", repetitiveCSS),
+		},
+		[]string{"a.css", "a.css.gz", "b.html", "b.html.gz", "c.svg"},
+	},
+	// a compressed copy the source already has is left alone
+	{
+		PrecompressConfig{},
+		gosnap.FileMapType{
+			"a.css":    gosnap.NewFile("a.css", repetitiveCSS),
+			"a.css.gz": gosnap.NewFile("a.css.gz", []byte("from the source")),
+		},
+		[]string{"a.css", "a.css.gz"},
+	},
+}
+
+func TestPrecompress(t *testing.T) {
+	for i, test := range precompressTests {
+		if err := Precompress(test.config)(test.fileMap); err != nil {
+			t.Error("Precompress errored unexpectedly:", err, "in case", i)
+		}
+
+		if paths := sortedPaths(test.fileMap); !reflect.DeepEqual(paths, test.expected) {
+			t.Error(
+				"Expected", test.expected,
+				"instead got", paths,
+				"in case", i,
+			)
+		}
+	}
+
+	if content := string(precompressTests[5].fileMap["a.css.gz"].Content); content != "from the source" {
+		t.Error("Expected the compressed copy from the source to stay the same, instead got", content)
+	}
+}
+
+func TestPrecompressHeaders(t *testing.T) {
+	file := gosnap.NewFile("a.css", repetitiveCSS)
+	file.Headers().Set("Cache-Control", "max-age=60")
+	fileMap := gosnap.FileMapType{"a.css": file}
+
+	if err := Precompress(PrecompressConfig{})(fileMap); err != nil {
+		t.Fatal("Precompress errored unexpectedly:", err)
+	}
+
+	compressed := fileMap["a.css.gz"]
+	header := compressed.Headers()
+
+	if header.Get("Content-Type") != file.Headers().Get("Content-Type") || header.Get("Cache-Control") != "max-age=60" || header.Get("Content-Encoding") != "gzip" {
+		t.Error("Expected the copy to have the headers of the file and a Content-Encoding, instead got", header)
+	}
+	if header.Get("Content-Length") != strconv.Itoa(len(compressed.Content)) || header.Get("Content-MD5") == file.Headers().Get("Content-MD5") {
+		t.Error("Expected the copy to describe its own content, instead got", header)
+	}
+	if file.Headers().Get("Content-Encoding") != "" {
+		t.Error("Expected the file itself to keep its headers, instead got", file.Headers())
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed.Content))
+	if err != nil {
+		t.Fatal("Could not read the compressed copy:", err)
+	}
+
+	if content, err := ioutil.ReadAll(reader); err != nil || !bytes.Equal(content, repetitiveCSS) {
+		t.Error("Expected the copy to decompress to the file, instead got", string(content), err)
+	}
+}
+
+func TestPrecompressBrotli(t *testing.T) {
+	fileMap := gosnap.FileMapType{"a.css": gosnap.NewFile("a.css", repetitiveCSS)}
+	err := Precompress(PrecompressConfig{Brotli: true})(fileMap)
+
+	// brotli is only there with the brotli build tag
+	if brotliEncoding == nil {
+		if err == nil {
+			t.Error("Expected Brotli to error without the brotli build tag")
+		}
+		return
+	}
+
+	if err != nil {
+		t.Error("Precompress errored unexpectedly:", err)
+	}
+	if compressed, exists := fileMap["a.css.br"]; !exists || compressed.Headers().Get("Content-Encoding") != "br" {
+		t.Error("Expected a brotli copy, instead got", sortedPaths(fileMap))
+	}
+}
@@ -0,0 +1,161 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// RedirectFiles says how old urls are sent on to where files are now, a kind of redirect is left out if its
+// path is empty or it is not turned on
+type RedirectFiles struct {
+	// Netlify style _redirects file, its redirects win over the stubs
+	Netlify string
+	// nginx rewrite rules to include in a server block
+	Nginx string
+	// html pages at the old urls which send browsers on with a meta refresh, for hosts without redirects
+	Stubs bool
+}
+
+// one old url of a file
+type redirect struct {
+	from string
+	to   string
+}
+
+// where the stub for an old url is written, urls of directories get an index.html
+func stubPath(alias string) string {
+	stub := strings.TrimPrefix(alias, "/")
+
+	if stub == "" || strings.HasSuffix(stub, "/") || path.Ext(stub) == "" {
+		stub = path.Join(stub, "index.html")
+	}
+
+	return stub
+}
+
+func aliases(file *gosnap.GoSnapFile) ([]string, error) {
+	all := []string{}
+
+	for _, key := range []string{"aliases", "redirect_from"} {
+		if !file.Data.Has(key) {
+			continue
+		}
+
+		list, err := file.Data.StringSlice(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, list...)
+	}
+
+	return all, nil
+}
+
+// every redirect in order of the files, stub paths are checked against the real files and each other
+func redirects(fileMap gosnap.FileMapType) ([]redirect, error) {
+	found := []redirect{}
+	claimed := map[string]string{}
+
+	for _, filePath := range sortedPaths(fileMap) {
+		list, err := aliases(fileMap[filePath])
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not read aliases of %v", filePath)
+		}
+
+		for _, alias := range list {
+			from := "/" + strings.TrimPrefix(alias, "/")
+			stub := stubPath(from)
+
+			if _, exists := fileMap[stub]; exists {
+				return nil, errors.Errorf("Alias %v of %v is the same as the file %v", alias, filePath, stub)
+			}
+			if other, exists := claimed[stub]; exists {
+				return nil, errors.Errorf("Alias %v of %v is already an alias of %v", alias, filePath, other)
+			}
+
+			claimed[stub] = filePath
+			found = append(found, redirect{from: from, to: absoluteURL("", filePath)})
+		}
+	}
+
+	return found, nil
+}
+
+func redirectStub(to string) []byte {
+	escaped := html.EscapeString(to)
+
+	return []byte(fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Redirecting to %[1]v</title>
+<link rel="canonical" href="%[1]v">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url=%[1]v">
+</head>
+<body><a href="%[1]v">Redirecting to %[1]v</a></body>
+</html>
+`, escaped))
+}
+
+// the ! makes Netlify redirect even though a stub exists at the old url
+func netlifyRedirects(found []redirect) []byte {
+	var buffer bytes.Buffer
+
+	for _, redirect := range found {
+		fmt.Fprintf(&buffer, "%v %v 301!\n", redirect.from, redirect.to)
+	}
+
+	return buffer.Bytes()
+}
+
+func nginxRedirects(found []redirect) []byte {
+	var buffer bytes.Buffer
+
+	for _, redirect := range found {
+		fmt.Fprintf(&buffer, "rewrite %v %v permanent;\n", nginxQuote("^"+regexp.QuoteMeta(redirect.from)+"$"), nginxQuote(redirect.to))
+	}
+
+	return buffer.Bytes()
+}
+
+// Redirects keeps old urls of files working, files list them as "aliases" or "redirect_from" in their frontmatter.
+// It is an error for an old url to be where a file is now or to be claimed by two files. Run it after plugins
+// which move files, like Permalinks, and before Sitemap so that the stubs are left out of the sitemap.
+func Redirects(files RedirectFiles) gosnap.Plugin {
+	return gosnap.Named("plugins.Redirects", func(fileMap gosnap.FileMapType) error {
+		found, err := redirects(fileMap)
+
+		if err != nil {
+			return err
+		}
+
+		if files.Stubs {
+			for _, redirect := range found {
+				stub := gosnap.NewFile(stubPath(redirect.from), redirectStub(redirect.to))
+				stub.Data = gosnap.FrontmatterValueType{"sitemap": false, "redirect": redirect.to}
+
+				fileMap[stubPath(redirect.from)] = stub
+			}
+		}
+
+		if files.Netlify != "" {
+			fileMap[files.Netlify] = gosnap.NewFile(files.Netlify, netlifyRedirects(found))
+		}
+		if files.Nginx != "" {
+			fileMap[files.Nginx] = gosnap.NewFile(files.Nginx, nginxRedirects(found))
+		}
+
+		return nil
+	})
+}
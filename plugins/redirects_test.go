@@ -0,0 +1,77 @@
+package plugins
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+type redirectsStruct struct {
+	fileMap         gosnap.FileMapType
+	expected        []string
+	expectedNetlify string
+	expectedError   bool
+}
+
+var redirectsTests = []redirectsStruct{
+	{
+		gosnap.FileMapType{
+			"blog/a/index.html": newPage(gosnap.FrontmatterValueType{"aliases": []interface{}{"/old/a/", "a.html"}}),
+			"b.html":            newPage(gosnap.FrontmatterValueType{"redirect_from": "/old/b"}),
+		},
+		[]string{"_redirects", "a.html", "b.html", "blog/a/index.html", "old/a/index.html", "old/b/index.html"},
+		"/old/b /b.html 301!\n/old/a/ /blog/a/ 301!\n/a.html /blog/a/ 301!\n",
+		false,
+	},
+	// an alias where a file already is
+	{
+		gosnap.FileMapType{
+			"about/index.html": newPage(gosnap.FrontmatterValueType{}),
+			"team.html":        newPage(gosnap.FrontmatterValueType{"aliases": []interface{}{"/about/"}}),
+		},
+		[]string{"about/index.html", "team.html"},
+		"",
+		true,
+	},
+	// two files with the same alias, /old and /old/ are both served by old/index.html
+	{
+		gosnap.FileMapType{
+			"a.html": newPage(gosnap.FrontmatterValueType{"aliases": []interface{}{"/old"}}),
+			"b.html": newPage(gosnap.FrontmatterValueType{"aliases": []interface{}{"/old/"}}),
+		},
+		[]string{"a.html", "b.html"},
+		"",
+		true,
+	},
+}
+
+func TestRedirects(t *testing.T) {
+	for i, test := range redirectsTests {
+		err := Redirects(RedirectFiles{Netlify: "_redirects", Stubs: true})(test.fileMap)
+
+		if (err != nil) != test.expectedError {
+			t.Error(
+				"Expected error", test.expectedError,
+				"in case", i,
+				"instead got", err,
+			)
+		}
+
+		if filePaths := sortedPaths(test.fileMap); !reflect.DeepEqual(filePaths, test.expected) {
+			t.Error(
+				"Expected", test.expected,
+				"instead got", filePaths,
+				"in case", i,
+			)
+		}
+
+		if netlify, exists := test.fileMap["_redirects"]; exists && string(netlify.Content) != test.expectedNetlify {
+			t.Error(
+				"Expected", test.expectedNetlify,
+				"instead got", string(netlify.Content),
+				"in case", i,
+			)
+		}
+	}
+}
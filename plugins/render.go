@@ -1,27 +1,222 @@
 package plugins
 
 import (
+	"bytes"
+	htmltemplate "html/template"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+
 	"github.com/caeost/gosnap"
 	"github.com/pkg/errors"
-	"text/template"
 )
 
+// files marked with "template: true" are rendered as templates with their frontmatter as data
+func isTemplate(file *gosnap.GoSnapFile) bool {
+	return file.Data["template"] == true
+}
+
+// files marked with "partial: true" can be used by other templates under their path,
+// either with {{template "path"}} or as a layout, and are not written out themselves
+func isPartial(file *gosnap.GoSnapFile) bool {
+	return file.Data["partial"] == true
+}
+
+func layoutName(data gosnap.FrontmatterValueType) string {
+	name, _ := data.String("layout")
+
+	return name
+}
+
+// html files are escaped according to their context by html/template unless their frontmatter says "escape: false",
+// other files can opt in with "escape: true"
+func escapeHTML(filePath string, file *gosnap.GoSnapFile) bool {
+	if escape, err := file.Data.Bool("escape"); err == nil {
+		return escape
+	}
+
+	extension := strings.ToLower(path.Ext(filePath))
+
+	return extension == ".html" || extension == ".htm"
+}
+
+type templateSet interface {
+	ExecuteTemplate(io.Writer, string, interface{}) error
+}
+
+// every template and partial is parsed into both sets so that escaped and unescaped files can share partials
+type templateSets struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+func (sets templateSets) pick(escape bool) templateSet {
+	if escape {
+		return sets.html
+	}
+
+	return sets.text
+}
+
+// parse every template and partial into one set so that they can refer to each other
+func parseTemplates(fileMap gosnap.FileMapType) (templateSets, error) {
+	sets := templateSets{text: template.New(""), html: htmltemplate.New("")}
+
+	for filePath, file := range fileMap {
+		if isTemplate(file) || isPartial(file) {
+			if _, err := sets.text.New(filePath).Parse(string(file.Content)); err != nil {
+				return sets, errors.Wrapf(err, "Could not parse template in %v", filePath)
+			}
+			if _, err := sets.html.New(filePath).Parse(string(file.Content)); err != nil {
+				return sets, errors.Wrapf(err, "Could not parse template in %v", filePath)
+			}
+		}
+	}
+
+	return sets, nil
+}
+
+// data for a layout is its own frontmatter overridden by the frontmatter of the page, with the page itself as content
+func layoutData(layout gosnap.FrontmatterValueType, page gosnap.FrontmatterValueType, content interface{}) gosnap.FrontmatterValueType {
+	data := make(gosnap.FrontmatterValueType, len(layout)+len(page)+1)
+
+	for key, value := range layout {
+		data[key] = value
+	}
+	for key, value := range page {
+		data[key] = value
+	}
+	data["content"] = content
+
+	return data
+}
+
+// renders the file without changing it, returning its new content and the content as it was before going into layouts
+func renderFile(sets templateSets, partials gosnap.FileMapType, filePath string, file *gosnap.GoSnapFile) ([]byte, []byte, error) {
+	escape := escapeHTML(filePath, file)
+	set := sets.pick(escape)
+	content := file.Content
+
+	if isTemplate(file) {
+		var buffer bytes.Buffer
+
+		if err := set.ExecuteTemplate(&buffer, filePath, file.Data); err != nil {
+			return nil, nil, errors.Wrapf(err, "Could not render template in %v", filePath)
+		}
+
+		content = buffer.Bytes()
+	}
+
+	body := content
+
+	// wrap the content in its layout, and that in its layout, until there are no more
+	data := file.Data
+	used := make(map[string]bool)
+
+	for layout := layoutName(file.Data); layout != ""; {
+		layoutFile, exists := partials[layout]
+
+		if !exists {
+			return nil, nil, errors.Errorf("Layout %v used by %v is not a partial", layout, filePath)
+		}
+		if used[layout] {
+			return nil, nil, errors.Errorf("Layout %v used by %v includes itself", layout, filePath)
+		}
+		used[layout] = true
+
+		// the content has already been rendered so it must not be escaped a second time
+		if escape {
+			data = layoutData(layoutFile.Data, data, htmltemplate.HTML(content))
+		} else {
+			data = layoutData(layoutFile.Data, data, string(content))
+		}
+
+		var buffer bytes.Buffer
+
+		if err := set.ExecuteTemplate(&buffer, layout, data); err != nil {
+			return nil, nil, errors.Wrapf(err, "Could not render layout %v for %v", layout, filePath)
+		}
+
+		content = buffer.Bytes()
+		layout = layoutName(layoutFile.Data)
+	}
+
+	return content, body, nil
+}
+
+// what a file is changed to once every file has been rendered
+type rendered struct {
+	content []byte
+	body    []byte
+}
+
+// RenderFile renders a single file on its own, for use with gosnap.Each. It can only use templates defined in the
+// file itself, use Render for partials and layouts.
+func RenderFile(filePath string, file *gosnap.GoSnapFile) error {
+	sets, err := parseTemplates(gosnap.FileMapType{filePath: file})
+
+	if err != nil {
+		return err
+	}
+
+	// without partials a layout is an error, so there is no body to keep
+	content, _, err := renderFile(sets, nil, filePath, file)
+
+	if err != nil {
+		return err
+	}
+
+	file.Content = content
+
+	return nil
+}
+
+// Render executes templates and wraps files in their layouts, partials are removed from the file map.
+// Files with a layout keep their content from before the layout as "content" in their frontmatter, for feeds and the like.
+// Templates which look at other files, through collections for example, always see them as they were before Render.
 func Render(fileMap gosnap.FileMapType) error {
+	sets, err := parseTemplates(fileMap)
+
+	if err != nil {
+		return err
+	}
+
+	partials := make(gosnap.FileMapType)
+
 	for filePath, file := range fileMap {
-		if file.Data["template"] == true {
-			tem, err := template.New(filePath).Parse(string(file.Content))
+		if isPartial(file) {
+			partials[filePath] = file
+			delete(fileMap, filePath)
+		}
+	}
 
-			if err != nil {
-				return errors.Wrapf(err, "Could not parse template in %v", filePath)
-			}
+	// content and frontmatter can be read by the templates of other files so they are only changed once they are all done
+	var mutex sync.Mutex
+	results := make(map[*gosnap.GoSnapFile]rendered)
 
-			// clear out Content since it is the template and no longer necessary
-			file.Content = []byte{}
-			err = tem.ExecuteTemplate(file, tem.Name(), file.Data)
+	err = gosnap.Each(func(filePath string, file *gosnap.GoSnapFile) error {
+		content, body, err := renderFile(sets, partials, filePath, file)
 
-			if err != nil {
-				return errors.Wrapf(err, "Could not render template in %v", filePath)
-			}
+		if err == nil {
+			mutex.Lock()
+			results[file] = rendered{content, body}
+			mutex.Unlock()
+		}
+
+		return err
+	})(fileMap)
+
+	if err != nil {
+		return err
+	}
+
+	for file, result := range results {
+		file.Content = result.content
+
+		if layoutName(file.Data) != "" {
+			file.Data["content"] = string(result.body)
 		}
 	}
 
@@ -0,0 +1,128 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/caeost/gosnap"
+)
+
+func newTemplate(content string, data gosnap.FrontmatterValueType) *gosnap.GoSnapFile {
+	if data == nil {
+		data = gosnap.FrontmatterValueType{}
+	}
+	data["template"] = true
+
+	return &gosnap.GoSnapFile{Content: []byte(content), Data: data}
+}
+
+func TestRenderCollectionContent(t *testing.T) {
+	// the listing reads the posts while they are being rendered, it has to see them as they were every time
+	for i := 0; i < 50; i++ {
+		fileMap := gosnap.FileMapType{
+			"index.txt": newTemplate(`{{range .collections.posts}}{{printf "%s" .File.Content}};{{end}}`, nil),
+		}
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			fileMap["posts/"+name+".txt"] = newTemplate(name+"{{.title}}", gosnap.FrontmatterValueType{"title": "!"})
+		}
+
+		if err := Collections(Collection{Name: "posts", Pattern: "posts/*"})(fileMap); err != nil {
+			t.Fatal("Collections errored unexpectedly:", err)
+		}
+		if err := Render(fileMap); err != nil {
+			t.Fatal("Render errored unexpectedly:", err)
+		}
+
+		expected := "a{{.title}};b{{.title}};c{{.title}};d{{.title}};e{{.title}};f{{.title}};g{{.title}};h{{.title}};"
+
+		if string(fileMap["index.txt"].Content) != expected {
+			t.Fatal(
+				"Expected", expected,
+				"instead got", string(fileMap["index.txt"].Content),
+				"in run", i,
+			)
+		}
+		if string(fileMap["posts/a.txt"].Content) != "a!" {
+			t.Fatal("Expected a! instead got", string(fileMap["posts/a.txt"].Content))
+		}
+	}
+}
+
+func TestRenderFile(t *testing.T) {
+	file := newTemplate(`{{define "name"}}<b>{{.name}}</b>{{end}}hello {{template "name" .}}`, gosnap.FrontmatterValueType{"name": "<gopher>"})
+
+	if err := gosnap.Each(RenderFile)(gosnap.FileMapType{"a.html": file}); err != nil {
+		t.Fatal("RenderFile errored unexpectedly:", err)
+	}
+	if string(file.Content) != "hello <b>&lt;gopher&gt;</b>" {
+		t.Error("Expected hello <b>&lt;gopher&gt;</b> instead got", string(file.Content))
+	}
+
+	withLayout := newTemplate("page", gosnap.FrontmatterValueType{"layout": "layout.html"})
+
+	if err := RenderFile("b.html", withLayout); err == nil {
+		t.Error("Expected an error for a layout without partials")
+	}
+}
+
+type escapeStruct struct {
+	filePath string
+	data     gosnap.FrontmatterValueType
+	expected string
+}
+
+var escapeTests = []escapeStruct{
+	// html files escape frontmatter values
+	{"a.html", gosnap.FrontmatterValueType{}, "<p>&lt;b&gt;Tom &amp; Jerry&lt;/b&gt;</p>"},
+	{"a.htm", gosnap.FrontmatterValueType{}, "<p>&lt;b&gt;Tom &amp; Jerry&lt;/b&gt;</p>"},
+	// other files do not
+	{"a.txt", gosnap.FrontmatterValueType{}, "<p><b>Tom & Jerry</b></p>"},
+	// unless their frontmatter says otherwise
+	{"a.html", gosnap.FrontmatterValueType{"escape": false}, "<p><b>Tom & Jerry</b></p>"},
+	{"a.txt", gosnap.FrontmatterValueType{"escape": true}, "<p>&lt;b&gt;Tom &amp; Jerry&lt;/b&gt;</p>"},
+}
+
+func TestRenderEscape(t *testing.T) {
+	for i, test := range escapeTests {
+		test.data["title"] = "<b>Tom & Jerry</b>"
+		file := newTemplate("<p>{{.title}}</p>", test.data)
+
+		if err := Render(gosnap.FileMapType{test.filePath: file}); err != nil {
+			t.Fatal("Render errored unexpectedly in case", i, err)
+		}
+
+		if string(file.Content) != test.expected {
+			t.Error(
+				"Expected", test.expected,
+				"instead got", string(file.Content),
+				"in case", i,
+			)
+		}
+	}
+}
+
+func TestRenderLayoutEscape(t *testing.T) {
+	fileMap := gosnap.FileMapType{
+		"layout.html": &gosnap.GoSnapFile{
+			Content: []byte("<main>{{.content}}</main><footer>{{.title}}</footer>"),
+			Data:    gosnap.FrontmatterValueType{"partial": true},
+		},
+		"a.html": newTemplate("<p>{{.title}}</p>", gosnap.FrontmatterValueType{"layout": "layout.html", "title": "Tom & Jerry"}),
+	}
+
+	if err := Render(fileMap); err != nil {
+		t.Fatal("Render errored unexpectedly:", err)
+	}
+
+	// the page is escaped once when it is rendered and put into the layout as it is
+	expected := "<main><p>Tom &amp; Jerry</p></main><footer>Tom &amp; Jerry</footer>"
+
+	if string(fileMap["a.html"].Content) != expected {
+		t.Error("Expected", expected, "instead got", string(fileMap["a.html"].Content))
+	}
+	if fileMap["a.html"].Data["content"] != "<p>Tom &amp; Jerry</p>" {
+		t.Error("Expected the content before the layout instead got", fileMap["a.html"].Data["content"])
+	}
+	if _, exists := fileMap["layout.html"]; exists {
+		t.Error("Expected the layout to be removed from the file map")
+	}
+}
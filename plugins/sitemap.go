@@ -0,0 +1,152 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/caeost/gosnap"
+	"github.com/pkg/errors"
+)
+
+// SitemapConfig configures the sitemap.xml, and optionally robots.txt, generated from every html file.
+// Files can set "sitemap: false", "priority" and "changefreq" in their frontmatter.
+type SitemapConfig struct {
+	// url the site is served from, for example "https://example.com", required since sitemaps need absolute urls
+	BaseURL string
+	// defaults to sitemap.xml
+	Path string
+	// robots.txt is only written if there are rules, it always points to the sitemap
+	Robots []RobotsRule
+}
+
+type RobotsRule struct {
+	// defaults to *
+	UserAgent string
+	Allow     []string
+	Disallow  []string
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+func isHTML(filePath string) bool {
+	extension := strings.ToLower(path.Ext(filePath))
+
+	return extension == ".html" || extension == ".htm"
+}
+
+// url of a file, index.html is left off so that directories are linked to
+func absoluteURL(baseURL string, filePath string) string {
+	if path.Base(filePath) == "index.html" {
+		filePath = strings.TrimSuffix(filePath, "index.html")
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + filePath
+}
+
+// when the source of a file was last changed according to its headers. Files without a Last-Modified have no
+// source to go by, their FileInfo only knows when they were generated which changes with every build.
+func lastModified(file *gosnap.GoSnapFile) time.Time {
+	modified, err := http.ParseTime(file.Headers().Get("Last-Modified"))
+
+	if err != nil {
+		return time.Time{}
+	}
+
+	return modified
+}
+
+func sitemapEntry(baseURL string, filePath string, file *gosnap.GoSnapFile) sitemapURL {
+	entry := sitemapURL{Loc: absoluteURL(baseURL, filePath)}
+
+	if modified := lastModified(file); !modified.IsZero() {
+		entry.LastMod = modified.UTC().Format(time.RFC3339)
+	}
+	if changeFreq, exists := file.Data["changefreq"]; exists {
+		entry.ChangeFreq = fmt.Sprint(changeFreq)
+	}
+	if priority, exists := file.Data["priority"]; exists {
+		entry.Priority = fmt.Sprint(priority)
+	}
+
+	return entry
+}
+
+func robots(rules []RobotsRule, sitemapURL string) []byte {
+	var buffer bytes.Buffer
+
+	for _, rule := range rules {
+		userAgent := rule.UserAgent
+		if userAgent == "" {
+			userAgent = "*"
+		}
+
+		fmt.Fprintf(&buffer, "User-agent: %v\n", userAgent)
+		for _, allow := range rule.Allow {
+			fmt.Fprintf(&buffer, "Allow: %v\n", allow)
+		}
+		for _, disallow := range rule.Disallow {
+			fmt.Fprintf(&buffer, "Disallow: %v\n", disallow)
+		}
+		buffer.WriteString("\n")
+	}
+
+	fmt.Fprintf(&buffer, "Sitemap: %v\n", sitemapURL)
+
+	return buffer.Bytes()
+}
+
+// Sitemap adds a sitemap listing every html file in the file map, run it after plugins which move files
+func Sitemap(sitemap SitemapConfig) gosnap.Plugin {
+	sitemapPath := sitemap.Path
+	if sitemapPath == "" {
+		sitemapPath = "sitemap.xml"
+	}
+
+	return gosnap.Named("plugins.Sitemap", func(fileMap gosnap.FileMapType) error {
+		if sitemap.BaseURL == "" {
+			return errors.Errorf("Could not build %v without a BaseURL, sitemaps only allow absolute urls", sitemapPath)
+		}
+
+		urlSet := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+		for _, filePath := range sortedPaths(fileMap) {
+			file := fileMap[filePath]
+
+			if !isHTML(filePath) || file.Data["sitemap"] == false || isPartial(file) {
+				continue
+			}
+
+			urlSet.URLs = append(urlSet.URLs, sitemapEntry(sitemap.BaseURL, filePath, file))
+		}
+
+		content, err := marshalXML(urlSet)
+
+		if err != nil {
+			return errors.Wrap(err, "Could not encode sitemap")
+		}
+
+		fileMap[sitemapPath] = gosnap.NewFile(sitemapPath, content)
+
+		if len(sitemap.Robots) > 0 {
+			fileMap["robots.txt"] = gosnap.NewFile("robots.txt", robots(sitemap.Robots, absoluteURL(sitemap.BaseURL, sitemapPath)))
+		}
+
+		return nil
+	})
+}
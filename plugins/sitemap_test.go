@@ -0,0 +1,95 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caeost/gosnap"
+)
+
+func TestSitemap(t *testing.T) {
+	fileMap := gosnap.FileMapType{
+		"index.html":   newPage(gosnap.FrontmatterValueType{"priority": 1.0}),
+		"a/index.html": newPage(gosnap.FrontmatterValueType{}),
+		"hidden.html":  newPage(gosnap.FrontmatterValueType{"sitemap": false}),
+		"style.css":    newPage(gosnap.FrontmatterValueType{}),
+	}
+
+	config := SitemapConfig{BaseURL: "https://example.com/", Robots: []RobotsRule{{Disallow: []string{"/a/"}}}}
+
+	if err := Sitemap(config)(fileMap); err != nil {
+		t.Fatal("Sitemap errored unexpectedly:", err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/a/</loc>
+  </url>
+  <url>
+    <loc>https://example.com/</loc>
+    <priority>1</priority>
+  </url>
+</urlset>
+`
+	if string(fileMap["sitemap.xml"].Content) != expected {
+		t.Error("Expected", expected, "instead got", string(fileMap["sitemap.xml"].Content))
+	}
+
+	expectedRobots := "User-agent: *\nDisallow: /a/\n\nSitemap: https://example.com/sitemap.xml\n"
+	if string(fileMap["robots.txt"].Content) != expectedRobots {
+		t.Error("Expected", expectedRobots, "instead got", string(fileMap["robots.txt"].Content))
+	}
+
+	// relative urls are not allowed in a sitemap or robots.txt
+	fileMap = gosnap.FileMapType{"index.html": newPage(gosnap.FrontmatterValueType{})}
+
+	if err := Sitemap(SitemapConfig{Robots: config.Robots})(fileMap); err == nil {
+		t.Error("Expected an error without a BaseURL")
+	}
+	if _, exists := fileMap["robots.txt"]; exists {
+		t.Error("Expected no robots.txt without a BaseURL")
+	}
+}
+
+func TestSitemapLastModified(t *testing.T) {
+	source, err := ioutil.TempDir("", "gosnap-sitemap")
+	if err != nil {
+		t.Fatal("Could not create source directory:", err)
+	}
+	defer os.RemoveAll(source)
+
+	modified := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	filePath := filepath.Join(source, "page.html")
+
+	if err := ioutil.WriteFile(filePath, []byte("---\ntemplate: true\n---\n{{.title}}"), 0644); err != nil {
+		t.Fatal("Could not write source file:", err)
+	}
+	if err := os.Chtimes(filePath, modified, modified); err != nil {
+		t.Fatal("Could not set modification time:", err)
+	}
+
+	site := gosnap.GoSnap{Source: source, Logger: log.New(ioutil.Discard, "", 0)}
+
+	if err := site.Read(); err != nil {
+		t.Fatal("Read errored unexpectedly:", err)
+	}
+
+	// rendering replaces the content, the page still last changed when its source did
+	plugins := []gosnap.Plugin{Render, Sitemap(SitemapConfig{BaseURL: "https://example.com"})}
+
+	if err := gosnap.Run(site.FileMap, plugins); err != nil {
+		t.Fatal("Run errored unexpectedly:", err)
+	}
+
+	sitemap := string(site.FileMap["sitemap.xml"].Content)
+
+	if !strings.Contains(sitemap, "<lastmod>2020-05-06T07:08:09Z</lastmod>") {
+		t.Error("Expected the modification time of the source as lastmod in", sitemap)
+	}
+}